@@ -0,0 +1,51 @@
+// Command flogaudit checks the audit log files written by flog.AuditSink, example:
+//
+//	flogaudit -key-env AUDIT_KEY logs/audit.log
+//
+// it prints the first broken or missing record of every file, and exits with 1 if any file fails.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/fishjam/go-library/flog/auditverify"
+)
+
+func main() {
+	key := flag.String("key", "", "the HMAC key of the audit log")
+	keyEnv := flag.String("key-env", "", "the environment variable of the HMAC key, safer than -key")
+	flag.Usage = func() {
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [-key KEY | -key-env NAME] file...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *keyEnv != "" {
+		*key = os.Getenv(*keyEnv)
+	}
+
+	failed := false
+	for _, fileName := range flag.Args() {
+		result, err := auditverify.VerifyFile(fileName, []byte(*key))
+		var verifyErr *auditverify.Error
+		switch {
+		case errors.As(err, &verifyErr):
+			failed = true
+			fmt.Printf("%s: FAIL at %v, %d records before it are valid\n", fileName, verifyErr, result.Records)
+		case err != nil:
+			failed = true
+			fmt.Printf("%s: ERROR %v\n", fileName, err)
+		default:
+			fmt.Printf("%s: OK, %d records, last seq %d, last hash %s\n", fileName, result.Records, result.LastSeq, result.LastHash)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
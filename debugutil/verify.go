@@ -22,6 +22,9 @@ type Config struct {
 	MoreSkip 			int
 	Message     		string
 	IgnoreExceptions 	[]error
+	// Logger 用于输出 verify fail 的日志, 为 nil 时使用 "debugutil" 的 named logger.
+	// 其他包可以传入自己的 named logger(如 flog.GetLogger("multipart")), 以便单独配置
+	Logger 				flog.ILogger
 }
 
 //Notice:
@@ -32,13 +35,20 @@ type Config struct {
 
 var verifyAction = ACTION_LOG_ERROR
 
+// logger 使用独立的名字, 可以通过 flog.SetLoggerLevel("debugutil", ...) 单独配置
+var logger = flog.GetLogger("debugutil")
+
 // skip 表示跳过几个调用堆栈, 获取真正有意义的代码调用位置
 func checkAndHandleError(err error, msg string, action CheckErrorAction, skip int) {
+	checkAndHandleErrorWithLogger(logger, err, msg, action, skip+1)
+}
+
+func checkAndHandleErrorWithLogger(verifyLogger flog.ILogger, err error, msg string, action CheckErrorAction, skip int) {
 	if err != nil {
 		fileName, lineNo, funName := flog.GetCallStackInfo(skip)
 		switch action {
 		case ACTION_LOG_ERROR:
-			flog.WarnExWithPosf(fileName, lineNo, funName, "verify fail: err=%s(%s), msg=%q",
+			verifyLogger.WarnExWithPosf(fileName, lineNo, funName, "verify fail: err=%s(%s), msg=%q",
 				reflect.TypeOf(err).String(), err.Error(), msg)
 		case ACTION_FATAL_QUIT:
 			newMsg := fmt.Sprintf("%s:%d (%s) FAIL(%s), msg=%q\n",
@@ -57,6 +67,7 @@ func VerifyWithConfig(err error, config *Config) error{
 		ignore := false
 		moreSkip := 0
 		msg := err.Error()
+		var verifyLogger flog.ILogger = logger
 
 		if config != nil {
 			for _, ignoreExc := range config.IgnoreExceptions {
@@ -69,10 +80,13 @@ func VerifyWithConfig(err error, config *Config) error{
 				msg = config.Message
 			}
 			moreSkip = config.MoreSkip
+			if config.Logger != nil {
+				verifyLogger = config.Logger
+			}
 		}
 
 		if !ignore {
-			checkAndHandleError(err, msg, verifyAction, _SKIP_LEVEL + moreSkip)
+			checkAndHandleErrorWithLogger(verifyLogger, err, msg, verifyAction, _SKIP_LEVEL + moreSkip)
 		}
 	}
 	return err
@@ -1,10 +1,25 @@
 package debugutil
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"strings"
 	"testing"
 )
 
+type positionLogger struct {
+	lines []string
+}
+
+func (l *positionLogger) Debugf(format string, args ...any) {}
+
+func (l *positionLogger) Infof(format string, args ...any) {}
+
+func (l *positionLogger) WarnExWithPosf(fileName string, lineNo int, funName string, format string, args ...any) {
+	l.lines = append(l.lines, fmt.Sprintf("%s %s", funName, fmt.Sprintf(format, args...)))
+}
+
 // TestVerify this is a simple test functions for demonstrate how to use VerifyXxx functions.
 //
 // example: open a file should exist(local config file),
@@ -25,3 +40,11 @@ func someFunReturnValue() bool {
 func TestAssert(t *testing.T) {
 	Assert(someFunReturnValue())
 }
+
+func TestVerifyWithConfigLogger(t *testing.T) {
+	l := &positionLogger{}
+	_ = VerifyWithConfig(errors.New("seek fail"), &Config{Logger: l})
+	if len(l.lines) != 1 || !strings.HasPrefix(l.lines[0], "TestVerifyWithConfigLogger verify fail") {
+		t.Errorf("wrong lines %v", l.lines)
+	}
+}
@@ -0,0 +1,212 @@
+package flog
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// FullPolicy decides what to do when the queue of AsyncSink is full
+type FullPolicy int
+
+const (
+	// FullBlock blocks the caller until there is space, no log is lost
+	FullBlock FullPolicy = iota
+	// FullDropNewest drops the new record
+	FullDropNewest
+	// FullDropOldest drops the oldest record in the queue
+	FullDropOldest
+)
+
+// ErrSinkClosed is returned when write to a closed sink
+var ErrSinkClosed = errors.New("flog: sink closed")
+
+// AsyncOptions is the options of AsyncSink
+type AsyncOptions struct {
+	// QueueSize is the max count of buffered records, default is 1024
+	QueueSize int
+
+	// FullPolicy is the policy when the queue is full, default is FullBlock
+	FullPolicy FullPolicy
+
+	// OnError is called in the background goroutine when the sink returns error, can be nil
+	OnError func(r *Record, err error)
+}
+
+// AsyncStats is the statistics of AsyncSink
+type AsyncStats struct {
+	Queued        int    // current count of records in queue
+	Written       uint64 // records written to the sink, include failed
+	Failed        uint64 // records failed when write to the sink
+	DroppedNewest uint64 // records dropped by FullDropNewest
+	DroppedOldest uint64 // records dropped by FullDropOldest
+}
+
+// Dropped returns the total count of dropped records
+func (s AsyncStats) Dropped() uint64 {
+	return s.DroppedNewest + s.DroppedOldest
+}
+
+// AsyncSink buffers the records in a bounded ring queue, and writes them to the sink in a background goroutine,
+// so the caller is not blocked by the slow output(disk, network...).
+//
+// Flush or Close should be called before program exit, otherwise the buffered logs may be lost.
+type AsyncSink struct {
+	sink    Sink
+	options AsyncOptions
+
+	mu       sync.Mutex
+	notEmpty *sync.Cond // signaled when push record or close
+	changed  *sync.Cond // broadcast when the background goroutine wrote records, or pop by drop oldest
+	ring     []*Record
+	head     int // index of the oldest record
+	count    int
+	closed   bool
+	pushed   uint64 // total records accepted, include the records dropped by FullDropOldest
+	done     uint64 // total records written or dropped by FullDropOldest
+	stats    AsyncStats
+	finished chan struct{}
+}
+
+// NewAsyncSink returns the AsyncSink which writes to sink in background
+func NewAsyncSink(sink Sink, options AsyncOptions) *AsyncSink {
+	if options.QueueSize <= 0 {
+		options.QueueSize = 1024
+	}
+	as := &AsyncSink{
+		sink:     sink,
+		options:  options,
+		ring:     make([]*Record, options.QueueSize),
+		finished: make(chan struct{}),
+	}
+	as.notEmpty = sync.NewCond(&as.mu)
+	as.changed = sync.NewCond(&as.mu)
+	go as.run()
+	return as
+}
+
+// WriteRecord puts the record into the queue, the record should not be modified after written
+func (as *AsyncSink) WriteRecord(r *Record) error {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	for !as.closed && as.count == len(as.ring) {
+		switch as.options.FullPolicy {
+		case FullDropNewest:
+			as.stats.DroppedNewest++
+			return nil
+		case FullDropOldest:
+			as.ring[as.head] = nil
+			as.head = (as.head + 1) % len(as.ring)
+			as.count--
+			as.done++
+			as.stats.DroppedOldest++
+		default:
+			as.changed.Wait()
+		}
+	}
+	if as.closed {
+		return ErrSinkClosed
+	}
+	as.ring[(as.head+as.count)%len(as.ring)] = r
+	as.count++
+	as.pushed++
+	as.notEmpty.Signal()
+	return nil
+}
+
+func (as *AsyncSink) run() {
+	defer close(as.finished)
+	batch := make([]*Record, 0, len(as.ring))
+	for {
+		as.mu.Lock()
+		for as.count == 0 && !as.closed {
+			as.notEmpty.Wait()
+		}
+		if as.count == 0 && as.closed {
+			as.mu.Unlock()
+			return
+		}
+		// take all the records, so the writers are not blocked while writing
+		for as.count > 0 {
+			batch = append(batch, as.ring[as.head])
+			as.ring[as.head] = nil
+			as.head = (as.head + 1) % len(as.ring)
+			as.count--
+		}
+		as.mu.Unlock()
+
+		var failed uint64
+		for _, r := range batch {
+			if err := as.sink.WriteRecord(r); err != nil {
+				failed++
+				if as.options.OnError != nil {
+					as.options.OnError(r, err)
+				}
+			}
+		}
+
+		as.mu.Lock()
+		as.done += uint64(len(batch))
+		as.stats.Written += uint64(len(batch))
+		as.stats.Failed += failed
+		as.changed.Broadcast()
+		as.mu.Unlock()
+		batch = batch[:0]
+	}
+}
+
+// Flush waits until all the records queued before are written, then flushes the sink if it's a Flusher
+func (as *AsyncSink) Flush() error {
+	as.mu.Lock()
+	target := as.pushed
+	for as.done < target {
+		as.changed.Wait()
+	}
+	as.mu.Unlock()
+
+	if f, ok := as.sink.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Close writes all the queued records, then closes the sink if it's an io.Closer,
+// the later WriteRecord returns ErrSinkClosed.
+func (as *AsyncSink) Close() error {
+	as.mu.Lock()
+	if as.closed {
+		as.mu.Unlock()
+		return nil
+	}
+	as.closed = true
+	as.notEmpty.Broadcast()
+	as.changed.Broadcast()
+	as.mu.Unlock()
+
+	<-as.finished
+	if c, ok := as.sink.(io.Closer); ok {
+		return c.Close()
+	}
+	if f, ok := as.sink.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Stats returns the statistics
+func (as *AsyncSink) Stats() AsyncStats {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	stats := as.stats
+	stats.Queued = as.count
+	return stats
+}
+
+// EnableAsync wraps the sink of default logger with AsyncSink, and returns it for Stats,
+// call `flog.Flush()` or `flog.Close()` before program exit.
+func EnableAsync(options AsyncOptions) *AsyncSink {
+	as := NewAsyncSink(GetSink(), options)
+	SetSink(as)
+	return as
+}
@@ -0,0 +1,125 @@
+package flog
+
+import (
+	"bytes"
+	"errors"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// blockingSink blocks WriteRecord until release is closed
+type blockingSink struct {
+	mu       sync.Mutex
+	messages []string
+	release  chan struct{}
+	fail     bool
+}
+
+func (s *blockingSink) WriteRecord(r *Record) error {
+	<-s.release
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, r.Message)
+	if s.fail {
+		return errors.New("disk full")
+	}
+	return nil
+}
+
+func (s *blockingSink) Messages() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.messages...)
+}
+
+func TestAsyncSinkFlush(t *testing.T) {
+	var buf bytes.Buffer
+	as := NewAsyncSink(NewWriterSink(&buf, NewTextEncoder()), AsyncOptions{QueueSize: 4})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_ = as.WriteRecord(&Record{Level: InfoLevel, Message: "async line"})
+			}
+		}()
+	}
+	wg.Wait()
+	_ = as.Flush()
+	if count := strings.Count(buf.String(), "async line"); count != 400 {
+		t.Errorf("block policy should not lose lines, count=%d", count)
+	}
+	_ = as.Close()
+	if err := as.WriteRecord(&Record{}); !errors.Is(err, ErrSinkClosed) {
+		t.Errorf("write after close should fail, err=%v", err)
+	}
+}
+
+func TestAsyncSinkDropPolicy(t *testing.T) {
+	for _, policy := range []FullPolicy{FullDropNewest, FullDropOldest} {
+		sink := &blockingSink{release: make(chan struct{})}
+		as := NewAsyncSink(sink, AsyncOptions{QueueSize: 2, FullPolicy: policy})
+
+		// the first record is taken by the background goroutine and blocked, then queue is full after 2 records
+		_ = as.WriteRecord(&Record{Message: "0"})
+		for as.Stats().Queued != 0 {
+			runtime.Gosched()
+		}
+		for _, msg := range []string{"1", "2", "3", "4"} {
+			_ = as.WriteRecord(&Record{Message: msg})
+		}
+		stats := as.Stats()
+		close(sink.release)
+		_ = as.Close()
+
+		expected := "[0 1 2]"
+		if policy == FullDropOldest {
+			expected = "[0 3 4]"
+		}
+		if got := strings.Join(sink.Messages(), " "); "["+got+"]" != expected || stats.Dropped() != 2 {
+			t.Errorf("policy %d: messages=%s, stats=%+v", policy, got, stats)
+		}
+	}
+}
+
+func TestAsyncSinkError(t *testing.T) {
+	sink := &blockingSink{release: make(chan struct{}), fail: true}
+	close(sink.release)
+	var errCount int
+	as := NewAsyncSink(sink, AsyncOptions{OnError: func(r *Record, err error) { errCount++ }})
+	_ = as.WriteRecord(&Record{Message: "fail"})
+	_ = as.Close()
+	if stats := as.Stats(); stats.Failed != 1 || stats.Written != 1 || errCount != 1 {
+		t.Errorf("wrong stats %+v, errCount=%d", stats, errCount)
+	}
+}
+
+func TestEnableAsync(t *testing.T) {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	oldSink := GetSink()
+	oldExit := exitFunc
+	defer func() {
+		SetSink(oldSink)
+		SetOutput(stdLogWriter{})
+		exitFunc = oldExit
+	}()
+
+	as := EnableAsync(AsyncOptions{QueueSize: 16})
+	exitFunc = func(code int) {
+		// Fatal should flush the buffered logs before exit
+		if !strings.Contains(buf.String(), "fatal before exit") {
+			t.Errorf("buffered logs lost before exit: %s", buf.String())
+		}
+	}
+	Warnf("async warn")
+	Fatalf("fatal before exit")
+	_ = Close()
+	if !strings.Contains(buf.String(), "async warn") || as.Stats().Written != 2 {
+		t.Errorf("wrong output %s, stats=%+v", buf.String(), as.Stats())
+	}
+}
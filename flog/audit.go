@@ -0,0 +1,205 @@
+package flog
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/fishjam/go-library/flog/internal/auditchain"
+)
+
+// the keys of the hash chain in the audit record
+const (
+	AuditSeqKey  = auditchain.SeqKey
+	AuditPrevKey = auditchain.PrevKey
+	AuditHashKey = auditchain.HashKey
+)
+
+// AuditConfig is the config of AuditSink
+type AuditConfig struct {
+	// FileName is the audit log file, the new records are appended and chained to the last one
+	FileName string
+	// Key is the HMAC key, empty means plain SHA-256, which only proves the records are not edited partially
+	Key []byte
+	// Sync calls fsync after every record
+	Sync bool
+}
+
+// AuditSink writes the tamper-evident JSON records, every record has a monotonic "seq" and the "prev" hash,
+// and ends with the "hash" of the content before it, example:
+//
+//	{"time":"...","level":"INFO","file":"upload.go",...,"msg":"upload done","seq":2,"prev":"3a7b...","hash":"9c1e..."}
+//
+// so any edited, inserted or deleted record breaks the chain, it can be checked by auditverify.Verify or the
+// command cmd/flogaudit. the fields named "seq", "prev" and "hash" are renamed to "fields.xxx".
+type AuditSink struct {
+	config  AuditConfig
+	encoder *JSONEncoder
+
+	mu   sync.Mutex
+	file auditFile
+	size int64 // the offset of the next record, the partial record is truncated back to it
+	seq  uint64
+	prev string
+	// failed is set when a partial record can't be truncated, the sink refuses to write after the broken line
+	failed error
+	closed bool
+}
+
+// auditFile is *os.File, replaced in unit test
+type auditFile interface {
+	io.Writer
+	Truncate(size int64) error
+	Sync() error
+	Close() error
+}
+
+// NewAuditSink opens the file and continues the chain of the last record, returns error if the last record
+// is broken or signed by another key, so the chain is never continued from a tampered file.
+func NewAuditSink(config AuditConfig) (*AuditSink, error) {
+	file, err := os.OpenFile(config.FileName, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	s := &AuditSink{config: config, encoder: NewJSONEncoder(), file: file, prev: auditchain.GenesisHash}
+	if err = s.recover(file); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// recover reads the last record to continue the chain
+func (s *AuditSink) recover(file *os.File) error {
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	s.size = info.Size()
+	line, err := readLastLine(file)
+	if err != nil || len(line) == 0 {
+		return err
+	}
+	if !bytes.HasSuffix(line, []byte{'\n'}) {
+		return fmt.Errorf("flog: the last audit record of %s is incomplete", s.config.FileName)
+	}
+	entry, err := auditchain.ParseLine(line)
+	if err != nil {
+		return fmt.Errorf("flog: the last audit record of %s is broken: %w", s.config.FileName, err)
+	}
+	if auditchain.Hash(s.config.Key, entry.Body) != entry.Hash {
+		return fmt.Errorf("flog: the last audit record of %s is modified or signed by another key", s.config.FileName)
+	}
+	s.seq, s.prev = entry.Seq, entry.Hash
+	return nil
+}
+
+// readLastLine returns the last line with the line ending
+func readLastLine(file *os.File) ([]byte, error) {
+	info, err := file.Stat()
+	if err != nil || info.Size() == 0 {
+		return nil, err
+	}
+	size := info.Size()
+	for chunk := int64(4096); ; chunk *= 2 {
+		if chunk > size {
+			chunk = size
+		}
+		data := make([]byte, chunk)
+		if _, err = file.ReadAt(data, size-chunk); err != nil && err != io.EOF {
+			return nil, err
+		}
+		// skip the line ending of the last line
+		if index := bytes.LastIndexByte(bytes.TrimRight(data, "\n"), '\n'); index >= 0 {
+			return data[index+1:], nil
+		}
+		if chunk == size {
+			return data, nil
+		}
+	}
+}
+
+func (s *AuditSink) WriteRecord(r *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrSinkClosed
+	}
+	if s.failed != nil {
+		return s.failed
+	}
+
+	record, copied := *r, false
+	for i, f := range r.Fields {
+		if f.Key == AuditSeqKey || f.Key == AuditPrevKey || f.Key == AuditHashKey {
+			if !copied {
+				record.Fields, copied = append([]Field(nil), r.Fields...), true
+			}
+			record.Fields[i].Key = "fields." + f.Key
+		}
+	}
+	var buf bytes.Buffer
+	if err := s.encoder.Encode(&buf, &record); err != nil {
+		return err
+	}
+	// replace the "}\n" of JSONEncoder by the chain
+	buf.Truncate(buf.Len() - 2)
+	seq := s.seq + 1
+	buf.WriteString(`,"` + AuditSeqKey + `":`)
+	buf.WriteString(strconv.FormatUint(seq, 10))
+	buf.WriteString(`,"` + AuditPrevKey + `":"`)
+	buf.WriteString(s.prev)
+	buf.WriteByte('"')
+	hash := auditchain.Hash(s.config.Key, buf.Bytes())
+	buf.WriteString(auditchain.HashPrefix)
+	buf.WriteString(hash)
+	buf.WriteString("\"}\n")
+
+	if n, err := s.file.Write(buf.Bytes()); err != nil {
+		if n > 0 {
+			// the partial record breaks the line, remove it so the next record starts at a new line
+			if truncErr := s.file.Truncate(s.size); truncErr != nil {
+				s.failed = fmt.Errorf("flog: audit log %s has a partial record, write err=%v, truncate err=%w",
+					s.config.FileName, err, truncErr)
+			}
+		}
+		return err
+	}
+	s.size += int64(buf.Len())
+	s.seq, s.prev = seq, hash
+	if s.config.Sync {
+		return s.file.Sync()
+	}
+	return nil
+}
+
+// Seq returns the seq of the last record
+func (s *AuditSink) Seq() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.seq
+}
+
+// Flush calls fsync
+func (s *AuditSink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	return s.file.Sync()
+}
+
+func (s *AuditSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	_ = s.file.Sync()
+	return s.file.Close()
+}
@@ -0,0 +1,129 @@
+package flog
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fishjam/go-library/flog/internal/auditchain"
+)
+
+func newAuditTestRecord(msg string, fields ...Field) *Record {
+	r := newTestRecord()
+	r.Level, r.Message, r.Fields = InfoLevel, msg, fields
+	return r
+}
+
+func TestAuditSink(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "audit.log")
+	config := AuditConfig{FileName: fileName, Key: []byte("secret")}
+	sink, err := NewAuditSink(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := []Field{{Key: "hash", Value: "user"}, {Key: "size", Value: 10}}
+	_ = sink.WriteRecord(newAuditTestRecord("upload start", fields...))
+	_ = sink.WriteRecord(newAuditTestRecord("upload done"))
+	if fields[0].Key != "hash" || sink.Seq() != 2 {
+		t.Errorf("the record should not be changed, seq=%d", sink.Seq())
+	}
+	_ = sink.Close()
+	if sink.WriteRecord(newAuditTestRecord("closed")) != ErrSinkClosed {
+		t.Errorf("write after close should fail")
+	}
+
+	// continue the chain after reopen
+	sink, err = NewAuditSink(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = sink.WriteRecord(newAuditTestRecord("reopen"))
+	_ = sink.Close()
+
+	data, _ := os.ReadFile(fileName)
+	lines := strings.SplitAfter(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[0], `"fields.hash":"user"`) {
+		t.Fatalf("wrong audit log:\n%s", data)
+	}
+	prev := auditchain.GenesisHash
+	for i, line := range lines {
+		entry, err := auditchain.ParseLine([]byte(line))
+		if err != nil {
+			t.Fatalf("line %d: %v", i+1, err)
+		}
+		if entry.Seq != uint64(i+1) || entry.Prev != prev || auditchain.Hash(config.Key, entry.Body) != entry.Hash {
+			t.Errorf("line %d: wrong chain %+v", i+1, entry)
+		}
+		prev = entry.Hash
+	}
+
+	// refuse to continue the chain signed by another key or incomplete
+	if _, err = NewAuditSink(AuditConfig{FileName: fileName, Key: []byte("other")}); err == nil {
+		t.Errorf("open with another key should fail")
+	}
+	_ = os.WriteFile(fileName, append(data, `{"msg":"partial`...), 0644)
+	if _, err = NewAuditSink(config); err == nil {
+		t.Errorf("open with incomplete record should fail")
+	}
+}
+
+// partialFile writes half of the record and fails once, then fails to truncate if truncateErr is set
+type partialFile struct {
+	*os.File
+	fail        bool
+	truncateErr error
+}
+
+func (f *partialFile) Write(p []byte) (int, error) {
+	if f.fail {
+		f.fail = false
+		n, _ := f.File.Write(p[:len(p)/2])
+		return n, errors.New("disk full")
+	}
+	return f.File.Write(p)
+}
+
+func (f *partialFile) Truncate(size int64) error {
+	if f.truncateErr != nil {
+		return f.truncateErr
+	}
+	return f.File.Truncate(size)
+}
+
+func TestAuditSinkPartialWrite(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "audit.log")
+	config := AuditConfig{FileName: fileName}
+	sink, err := NewAuditSink(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = sink.WriteRecord(newAuditTestRecord("first"))
+	file := &partialFile{File: sink.file.(*os.File), fail: true}
+	sink.file = file
+	if sink.WriteRecord(newAuditTestRecord("partial")) == nil {
+		t.Errorf("partial write should fail")
+	}
+	_ = sink.WriteRecord(newAuditTestRecord("second"))
+	_ = sink.Close()
+
+	// the partial record is removed, the chain continues
+	data, _ := os.ReadFile(fileName)
+	lines := strings.SplitAfter(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 2 || strings.Contains(string(data), "partial") || !strings.Contains(lines[1], `"seq":2`) {
+		t.Fatalf("wrong audit log:\n%s", data)
+	}
+	if sink, err = NewAuditSink(config); err != nil {
+		t.Fatalf("reopen fail: %v", err)
+	}
+
+	// the sink refuses to write after the broken line if it can't be truncated
+	file = &partialFile{File: sink.file.(*os.File), fail: true, truncateErr: errors.New("read-only")}
+	sink.file = file
+	_ = sink.WriteRecord(newAuditTestRecord("partial"))
+	if err = sink.WriteRecord(newAuditTestRecord("after")); err == nil || !strings.Contains(err.Error(), "partial record") {
+		t.Errorf("write after broken line should fail, err=%v", err)
+	}
+	_ = sink.Close()
+}
@@ -0,0 +1,80 @@
+// Package auditverify checks the hash chain of the audit log written by flog.AuditSink
+package auditverify
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/fishjam/go-library/flog/internal/auditchain"
+)
+
+// Result is the summary of the verified records
+type Result struct {
+	Records  int
+	LastSeq  uint64
+	LastHash string
+}
+
+// Error is the first broken or missing record
+type Error struct {
+	Line   int    // 1-based line number in the file
+	Seq    uint64 // the expected seq
+	Reason string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d(seq %d): %s", e.Line, e.Seq, e.Reason)
+}
+
+// Verify checks the records read from r, the key is the HMAC key of the AuditSink(empty for plain SHA-256).
+// it returns *Error for the first record which is modified, inserted, or after a missing record, and the
+// Result of the records before it.
+func Verify(r io.Reader, key []byte) (Result, error) {
+	result := Result{LastHash: auditchain.GenesisHash}
+	reader := bufio.NewReader(r)
+	for lineNo := 1; ; lineNo++ {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			return result, nil
+		}
+		if err != nil && err != io.EOF {
+			return result, err
+		}
+		expected := result.LastSeq + 1
+		fail := func(format string, args ...any) (Result, error) {
+			return result, &Error{Line: lineNo, Seq: expected, Reason: fmt.Sprintf(format, args...)}
+		}
+		if !bytes.HasSuffix(line, []byte{'\n'}) {
+			return fail("incomplete record")
+		}
+
+		entry, parseErr := auditchain.ParseLine(line)
+		switch {
+		case parseErr != nil:
+			return fail("broken record: %v", parseErr)
+		case entry.Seq > expected:
+			return fail("missing record, got seq %d", entry.Seq)
+		case entry.Seq < expected:
+			return fail("unexpected seq %d, the record is duplicated or reordered", entry.Seq)
+		case entry.Prev != result.LastHash:
+			return fail("prev hash mismatch, the chain is broken")
+		case auditchain.Hash(key, entry.Body) != entry.Hash:
+			return fail("hash mismatch, the record is modified or signed by another key")
+		}
+		result.Records++
+		result.LastSeq, result.LastHash = entry.Seq, entry.Hash
+	}
+}
+
+// VerifyFile is same as Verify for the file
+func VerifyFile(fileName string, key []byte) (Result, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return Result{}, err
+	}
+	defer file.Close()
+	return Verify(file, key)
+}
@@ -0,0 +1,83 @@
+package auditverify
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fishjam/go-library/flog"
+)
+
+// writeAuditLog writes count records and returns the lines
+func writeAuditLog(t *testing.T, key []byte, count int) []string {
+	fileName := filepath.Join(t.TempDir(), "audit.log")
+	sink, err := flog.NewAuditSink(flog.AuditConfig{FileName: fileName, Key: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= count; i++ {
+		_ = sink.WriteRecord(&flog.Record{Time: time.Now(), Level: flog.InfoLevel, Message: "upload " + strconv.Itoa(i)})
+	}
+	_ = sink.Close()
+	data, _ := os.ReadFile(fileName)
+	lines := strings.SplitAfter(string(data), "\n")
+	return lines[:len(lines)-1]
+}
+
+func TestVerify(t *testing.T) {
+	key := []byte("secret")
+	lines := writeAuditLog(t, key, 5)
+
+	result, err := Verify(strings.NewReader(strings.Join(lines, "")), key)
+	if err != nil || result.Records != 5 || result.LastSeq != 5 {
+		t.Fatalf("valid log: %+v, err=%v", result, err)
+	}
+	if result, err = Verify(strings.NewReader(""), key); err != nil || result.Records != 0 {
+		t.Errorf("empty log: %+v, err=%v", result, err)
+	}
+
+	replace := func(index int, line string) []string {
+		changed := append([]string(nil), lines...)
+		changed[index] = line
+		return changed
+	}
+	cases := []struct {
+		name   string
+		lines  []string
+		key    []byte
+		line   int
+		reason string
+	}{
+		{"modified", replace(2, strings.Replace(lines[2], "upload 3", "upload 9", 1)), key, 3, "hash mismatch"},
+		{"wrong key", lines, []byte("other"), 1, "hash mismatch"},
+		{"deleted", append(append([]string(nil), lines[:1]...), lines[2:]...), key, 2, "missing record"},
+		{"duplicated", append(append([]string(nil), lines[:2]...), lines[1:]...), key, 3, "unexpected seq"},
+		{"reordered", []string{lines[0], lines[2], lines[1]}, key, 2, "missing record"},
+		{"broken", replace(3, "not json\n"), key, 4, "broken record"},
+		{"truncated", replace(4, strings.TrimSuffix(lines[4], "\n")), key, 5, "incomplete record"},
+	}
+	for _, c := range cases {
+		result, err = Verify(strings.NewReader(strings.Join(c.lines, "")), c.key)
+		var verifyErr *Error
+		if !errors.As(err, &verifyErr) || verifyErr.Line != c.line || !strings.Contains(verifyErr.Reason, c.reason) ||
+			result.Records != c.line-1 {
+			t.Errorf("%s: wrong result %+v, err=%v", c.name, result, err)
+		}
+	}
+}
+
+func TestVerifyFile(t *testing.T) {
+	lines := writeAuditLog(t, nil, 2)
+	fileName := filepath.Join(t.TempDir(), "audit.log")
+	_ = os.WriteFile(fileName, []byte(strings.Join(lines, "")), 0644)
+	if result, err := VerifyFile(fileName, nil); err != nil || result.Records != 2 {
+		t.Errorf("verify file: %+v, err=%v", result, err)
+	}
+	if _, err := VerifyFile(fileName+".not_exist", nil); err == nil {
+		t.Errorf("verify not exist file should fail")
+	}
+}
@@ -0,0 +1,342 @@
+package flog
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// CallsiteInfo is one callsite recorded by the callsite registry
+type CallsiteInfo struct {
+	File     string `json:"file"` // full path of the source file
+	Line     int    `json:"line"`
+	Function string `json:"func"`  // full function name
+	Level    Level  `json:"level"` // the level of the first hit
+	Hits     uint64 `json:"hits"`
+	Enabled  bool   `json:"enabled"`  // set by EnableCallsites
+	Disabled bool   `json:"disabled"` // set by DisableCallsites
+}
+
+// the state of callsite
+const (
+	callsiteDefault int32 = iota // decided by the level and vmodule
+	callsiteOn
+	callsiteOff
+)
+
+// callsite is the state of one log call, state is checked by one atomic load after the callsite is found
+type callsite struct {
+	file     string
+	line     int
+	function string
+	level    Level
+	state    int32
+	hits     uint64
+}
+
+// callsiteRule is one EnableCallsites/DisableCallsites, the last matched rule decides the callsite
+type callsiteRule struct {
+	pattern string // the terms joined by space, the rule of the same pattern is replaced
+	terms   []string
+	enable  bool
+}
+
+type callsiteRegistry struct {
+	// pcs is pc => *callsite, the inlined calls of the same line have different pc but share one callsite
+	pcs sync.Map
+
+	// mu serializes the registration and rule changes, so a new callsite never misses a rule
+	mu    sync.Mutex
+	sites map[string]*callsite // "file:line" => *callsite
+	rules []callsiteRule
+
+	// enableRules is the count of the EnableCallsites rules, the log disabled by level skips the lookup when it's 0
+	enableRules int32
+}
+
+// _callsites is *callsiteRegistry, nil when the tracking is off, so the check is only one atomic load
+var _callsites atomic.Value
+
+func loadCallsiteRegistry() *callsiteRegistry {
+	registry, _ := _callsites.Load().(*callsiteRegistry)
+	return registry
+}
+
+// _callsitesMu serializes the creation and reset of the registry
+var _callsitesMu sync.Mutex
+
+// TrackCallsites starts(or stops) recording the callsites, like Linux dynamic debug every log call which is
+// checked by level(Debugf, Infof... except Fatal and Panic) is recorded with its hit count.
+// when the tracking is off(the default) the check costs one atomic load. when it's on the log disabled by level
+// (and vmodule) costs two atomic loads until some EnableCallsites rule exists, so it's not recorded before that;
+// the others get the pc by runtime.Callers and look up the callsite in a sync.Map(about the same cost as vmodule),
+// then check the state by one atomic load.
+// stop the tracking clears the recorded callsites and rules.
+func TrackCallsites(on bool) {
+	if on {
+		ensureCallsiteRegistry()
+		return
+	}
+	_callsitesMu.Lock()
+	defer _callsitesMu.Unlock()
+	_callsites.Store((*callsiteRegistry)(nil))
+}
+
+func ensureCallsiteRegistry() *callsiteRegistry {
+	_callsitesMu.Lock()
+	defer _callsitesMu.Unlock()
+	registry := loadCallsiteRegistry()
+	if registry == nil {
+		registry = &callsiteRegistry{}
+		_callsites.Store(registry)
+	}
+	return registry
+}
+
+// EnableCallsites outputs all the logs at the matched callsites whatever the level is, the tracking is started
+// if not. the pattern is terms separated by space, all the terms(path.Match syntax) must match:
+//   - "file:line" matches the base name or the full path of the file, example: "virtual_writer.go:*", "verify.go:42"
+//   - "func=name" matches the short name, the name without package or the full name of the function,
+//     example: "func=Read", "func=(*VirtualWriter).*"
+//
+// the last matched rule decides the callsite, and the rule of the same pattern is replaced, so
+// EnableCallsites and DisableCallsites can be called repeatedly. the rule also applies to the callsites hit later,
+// returns the count of the matched callsites hit already.
+func EnableCallsites(pattern string) (int, error) {
+	return updateCallsites(pattern, true)
+}
+
+// DisableCallsites suppresses all the logs(except Fatal and Panic) at the matched callsites whatever the level is,
+// example: switch off a noisy line. the pattern is same as EnableCallsites, use TrackCallsites(false) to clear
+// all the rules.
+func DisableCallsites(pattern string) (int, error) {
+	return updateCallsites(pattern, false)
+}
+
+func updateCallsites(pattern string, enable bool) (int, error) {
+	terms, err := parseCallsitePattern(pattern)
+	if err != nil {
+		return 0, err
+	}
+	registry := ensureCallsiteRegistry()
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	rule := callsiteRule{pattern: strings.Join(terms, " "), terms: terms, enable: enable}
+	rules := make([]callsiteRule, 0, len(registry.rules)+1)
+	for _, old := range registry.rules {
+		if old.pattern != rule.pattern {
+			rules = append(rules, old)
+		}
+	}
+	registry.rules = append(rules, rule)
+	enableRules := int32(0)
+	for _, rule := range registry.rules {
+		if rule.enable {
+			enableRules++
+		}
+	}
+	atomic.StoreInt32(&registry.enableRules, enableRules)
+
+	count := 0
+	for _, site := range registry.sites {
+		if site.match(terms) {
+			count++
+		}
+		registry.apply(site)
+	}
+	return count, nil
+}
+
+func parseCallsitePattern(pattern string) ([]string, error) {
+	terms := strings.Fields(pattern)
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("flog: empty callsite pattern")
+	}
+	for _, term := range terms {
+		if _, err := path.Match(strings.TrimPrefix(term, "func="), ""); err != nil {
+			return nil, fmt.Errorf("flog: invalid callsite pattern %q: %w", term, err)
+		}
+	}
+	return terms, nil
+}
+
+// Callsites returns the recorded callsites sorted by file and line, nil if the tracking is off
+func Callsites() []CallsiteInfo {
+	registry := loadCallsiteRegistry()
+	if registry == nil {
+		return nil
+	}
+	registry.mu.Lock()
+	var result []CallsiteInfo
+	for _, site := range registry.sites {
+		result = append(result, CallsiteInfo{
+			File:     site.file,
+			Line:     site.line,
+			Function: site.function,
+			Level:    site.level,
+			Hits:     atomic.LoadUint64(&site.hits),
+			Enabled:  atomic.LoadInt32(&site.state) == callsiteOn,
+			Disabled: atomic.LoadInt32(&site.state) == callsiteOff,
+		})
+	}
+	registry.mu.Unlock()
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].File != result[j].File {
+			return result[i].File < result[j].File
+		}
+		return result[i].Line < result[j].Line
+	})
+	return result
+}
+
+// canEnable returns whether some EnableCallsites rule may enable the log disabled by level
+func (registry *callsiteRegistry) canEnable() bool {
+	return atomic.LoadInt32(&registry.enableRules) > 0
+}
+
+// hit records the callsite of pc and returns its state
+func (registry *callsiteRegistry) hit(pc uintptr, level Level) int32 {
+	value, ok := registry.pcs.Load(pc)
+	if !ok {
+		value = registry.register(pc, level)
+	}
+	site := value.(*callsite)
+	atomic.AddUint64(&site.hits, 1)
+	return atomic.LoadInt32(&site.state)
+}
+
+func (registry *callsiteRegistry) register(pc uintptr, level Level) *callsite {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if value, ok := registry.pcs.Load(pc); ok {
+		return value.(*callsite)
+	}
+	site := &callsite{level: level}
+	// the first frame is the innermost one when the call is inlined
+	if frames := framesOfPC(pc); len(frames) > 0 {
+		site.file, site.line, site.function = frames[0].File, frames[0].Line, frames[0].Function
+	}
+	key := site.file + ":" + strconv.Itoa(site.line)
+	if existing, ok := registry.sites[key]; ok {
+		site = existing
+	} else {
+		registry.apply(site)
+		if registry.sites == nil {
+			registry.sites = make(map[string]*callsite)
+		}
+		registry.sites[key] = site
+	}
+	registry.pcs.Store(pc, site)
+	return site
+}
+
+// apply sets the state of the site by the last matched rule, called with mu
+func (registry *callsiteRegistry) apply(site *callsite) {
+	state := callsiteDefault
+	for _, rule := range registry.rules {
+		if site.match(rule.terms) {
+			state = callsiteOff
+			if rule.enable {
+				state = callsiteOn
+			}
+		}
+	}
+	atomic.StoreInt32(&site.state, state)
+}
+
+func (site *callsite) match(terms []string) bool {
+	for _, term := range terms {
+		if !site.matchTerm(term) {
+			return false
+		}
+	}
+	return true
+}
+
+func (site *callsite) matchTerm(term string) bool {
+	if funcPattern := strings.TrimPrefix(term, "func="); funcPattern != term {
+		// example: "Read", "(*VirtualWriter).Read", "github.com/fishjam/go-library/mime/multipart.(*VirtualWriter).Read"
+		withoutPkg := strings.TrimPrefix(strings.TrimPrefix(site.function, packageOfFunc(site.function)), ".")
+		for _, name := range [...]string{shortFuncName(site.function), withoutPkg, site.function} {
+			if matched, _ := path.Match(funcPattern, name); matched {
+				return true
+			}
+		}
+		return false
+	}
+
+	filePattern, linePattern := term, "*"
+	if index := strings.LastIndexByte(term, ':'); index >= 0 {
+		filePattern, linePattern = term[:index], term[index+1:]
+	}
+	if matched, _ := path.Match(linePattern, strconv.Itoa(site.line)); !matched {
+		return false
+	}
+	for _, name := range [...]string{path.Base(site.file), shortFilePath(site.file), site.file} {
+		if matched, _ := path.Match(filePattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// callsiteRequest is the JSON body of PUT CallsiteHandler
+type callsiteRequest struct {
+	Enable  string `json:"enable,omitempty"`
+	Disable string `json:"disable,omitempty"`
+}
+
+// CallsiteHandler returns the http.Handler to list and switch the callsites at runtime, example:
+//
+//	http.Handle("/debug/flog/callsites", flog.CallsiteHandler())
+//
+//	curl http://localhost:8080/debug/flog/callsites
+//	curl -X PUT -d '{"enable":"virtual_writer.go:*"}' http://localhost:8080/debug/flog/callsites
+//
+// GET returns the recorded callsites, PUT enables or disables(force off) the matched callsites and returns the count.
+func CallsiteHandler() http.Handler {
+	return http.HandlerFunc(serveCallsites)
+}
+
+func serveCallsites(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		sites := Callsites()
+		if sites == nil {
+			sites = []CallsiteInfo{}
+		}
+		writeLevelJSON(w, http.StatusOK, sites)
+	case http.MethodPut:
+		var req callsiteRequest
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		err := decoder.Decode(&req)
+		if err == nil && (req.Enable == "") == (req.Disable == "") {
+			err = fmt.Errorf("flog: one of enable and disable is required")
+		}
+		var count int
+		if err == nil {
+			if req.Enable != "" {
+				count, err = EnableCallsites(req.Enable)
+			} else {
+				count, err = DisableCallsites(req.Disable)
+			}
+		}
+		if err != nil {
+			writeLevelJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		writeLevelJSON(w, http.StatusOK, map[string]int{"matched": count})
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		writeLevelJSON(w, http.StatusMethodNotAllowed,
+			map[string]string{"error": fmt.Sprintf("method %s not allowed", r.Method)})
+	}
+}
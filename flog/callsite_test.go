@@ -0,0 +1,199 @@
+package flog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func callsiteReadHelper(i int) {
+	Debugf("read %d", i)
+}
+
+func callsiteWriteHelper(i int) {
+	Debugf("write %d", i)
+}
+
+func callsiteNoisyHelper(i int) {
+	Infof("noisy %d", i)
+}
+
+func TestCallsites(t *testing.T) {
+	tl := useRecordLogger(t)
+	tl.SetLevel(InfoLevel)
+	defer TrackCallsites(false)
+
+	if Callsites() != nil {
+		t.Fatalf("callsites should be nil when the tracking is off")
+	}
+	TrackCallsites(true)
+	for i := 0; i < 3; i++ {
+		callsiteReadHelper(i)
+		callsiteWriteHelper(i)
+	}
+	// the logs disabled by level are not recorded until some rule may enable them
+	if tl.Count(DebugLevel, "") != 0 || len(Callsites()) != 0 {
+		t.Errorf("debug should be disabled by level without recording, callsites=%+v", Callsites())
+	}
+
+	// enable by function
+	count, err := EnableCallsites("func=callsiteRead*")
+	if err != nil || count != 0 {
+		t.Fatalf("enable by func: count=%d, err=%v", count, err)
+	}
+	for i := 0; i < 3; i++ {
+		callsiteReadHelper(10 + i)
+		callsiteWriteHelper(10 + i)
+	}
+	sites := Callsites()
+	if len(sites) != 2 || sites[0].Hits != 3 || sites[0].Level != DebugLevel || !sites[0].Enabled || sites[1].Enabled ||
+		!strings.HasSuffix(sites[0].Function, ".callsiteReadHelper") || !strings.HasSuffix(sites[0].File, "callsite_test.go") {
+		t.Fatalf("wrong callsites %+v", sites)
+	}
+	if tl.Count(DebugLevel, "read 1") != 3 || tl.Count(DebugLevel, "write 1") != 0 {
+		t.Errorf("wrong records %+v", tl.Records())
+	}
+
+	// enable by file and line, disable by line, the last matched rule wins
+	if count, _ = EnableCallsites("callsite_test.go:*"); count != 2 {
+		t.Errorf("enable by file: count=%d", count)
+	}
+	if count, _ = DisableCallsites("callsite_test.go:" + strconv.Itoa(sites[0].Line)); count != 1 {
+		t.Errorf("disable by line: count=%d", count)
+	}
+	tl.Reset()
+	callsiteReadHelper(20)
+	callsiteWriteHelper(20)
+	if tl.Count(DebugLevel, "read 20") != 0 || tl.Count(DebugLevel, "write 20") != 1 {
+		t.Errorf("wrong records %+v", tl.Records())
+	}
+
+	// the rule applies to the callsite hit later
+	Tracef("new callsite")
+	if tl.Count(TraceLevel, "new callsite") != 1 || len(Callsites()) != 3 || !Callsites()[0].Disabled {
+		t.Errorf("rule should apply to new callsite: %+v", Callsites())
+	}
+
+	// disable is force off even if the level allows
+	callsiteNoisyHelper(1)
+	if count, _ = DisableCallsites("func=callsiteNoisyHelper"); count != 1 {
+		t.Errorf("disable noisy: count=%d", count)
+	}
+	callsiteNoisyHelper(2)
+	if tl.Count(InfoLevel, "noisy 1") != 1 || tl.Count(InfoLevel, "noisy 2") != 0 {
+		t.Errorf("disabled callsite should be suppressed: %+v", tl.Records())
+	}
+
+	// the rule of the same pattern is replaced
+	registry := loadCallsiteRegistry()
+	ruleCount := len(registry.rules)
+	_, _ = EnableCallsites("func=callsiteNoisyHelper")
+	_, _ = DisableCallsites("  func=callsiteNoisyHelper ")
+	_, _ = EnableCallsites("func=callsiteNoisyHelper")
+	callsiteNoisyHelper(3)
+	if len(registry.rules) != ruleCount || tl.Count(InfoLevel, "noisy 3") != 1 {
+		t.Errorf("rule should be replaced, rules=%d, before=%d", len(registry.rules), ruleCount)
+	}
+
+	for _, pattern := range []string{"", "  ", "func=[", "[:1"} {
+		if _, err = EnableCallsites(pattern); err == nil {
+			t.Errorf("pattern %q should be invalid", pattern)
+		}
+	}
+
+	TrackCallsites(false)
+	tl.Reset()
+	callsiteWriteHelper(30)
+	if tl.Count(DebugLevel, "") != 0 || Callsites() != nil {
+		t.Errorf("stop tracking should clear the rules")
+	}
+}
+
+func TestCallsiteMatch(t *testing.T) {
+	site := &callsite{
+		file:     "/src/mime/multipart/virtual_writer.go",
+		line:     42,
+		function: "github.com/fishjam/go-library/mime/multipart.(*VirtualWriter).Read",
+	}
+	cases := []struct {
+		pattern string
+		matched bool
+	}{
+		{"virtual_writer.go:*", true},
+		{"virtual_writer.go", true},
+		{"multipart/virtual_writer.go:42", true},
+		{"virtual_writer.go:4?", true},
+		{"virtual_writer.go:43", false},
+		{"*.go:*", true},
+		{"func=Read", true},
+		{"func=(*VirtualWriter).*", true},
+		{"func=Write", false},
+		{"virtual_writer.go:* func=Read", true},
+		{"virtual_writer.go:* func=Write", false},
+	}
+	for _, c := range cases {
+		terms, err := parseCallsitePattern(c.pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if site.match(terms) != c.matched {
+			t.Errorf("match %q should be %v", c.pattern, c.matched)
+		}
+	}
+}
+
+func TestCallsiteHandler(t *testing.T) {
+	tl := useRecordLogger(t)
+	tl.SetLevel(InfoLevel)
+	defer TrackCallsites(false)
+	TrackCallsites(true)
+	callsiteNoisyHelper(1)
+
+	handler := CallsiteHandler()
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"enable":"func=callsiteNoisyHelper"}`)))
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != `{"matched":1}` {
+		t.Errorf("wrong PUT response %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	var sites []CallsiteInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &sites); err != nil || len(sites) != 1 || !sites[0].Enabled {
+		t.Errorf("wrong GET response %s, err=%v", w.Body.String(), err)
+	}
+
+	for _, body := range []string{`{}`, `{"enable":"a","disable":"b"}`, `{"other":1}`, `{"enable":"["}`} {
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("body %s should be bad request, got %d", body, w.Code)
+		}
+	}
+}
+
+func BenchmarkDisabledDebugWithCallsites(b *testing.B) {
+	oldLevel := GetLevel()
+	defer SetLevel(oldLevel)
+	defer TrackCallsites(false)
+	SetLevel(InfoLevel)
+	TrackCallsites(true)
+
+	// without EnableCallsites rule the disabled log skips the lookup, the rule makes every call lookup its callsite
+	b.Run("NoRule", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			Debugf("disabled debug")
+		}
+	})
+	b.Run("EnableRule", func(b *testing.B) {
+		_, _ = EnableCallsites("virtual_writer.go:*")
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			Debugf("disabled debug")
+		}
+	})
+}
@@ -0,0 +1,139 @@
+package flog
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// EnvNoColor disables the color of ConsoleEncoder when it's not empty, see https://no-color.org
+const EnvNoColor = "NO_COLOR"
+
+// ConsoleEncoder is the human friendly encoder for local development, example:
+//
+//	07:08:09.123 WARN  debugutil/verify.go:42    [100:7] verify fail upload_id=u1
+//
+// the level is colored by ANSI escape code when Color is true.
+type ConsoleEncoder struct {
+	// TimeFormat is the layout of time.Format, empty means no time
+	TimeFormat string
+	// Color outputs the level and field keys with ANSI color
+	Color bool
+	// HidePid hides the pid, only output goroutine id
+	HidePid bool
+	// FileWidth is the min width of "file:line" column, so the messages are aligned
+	FileWidth int
+}
+
+// NewConsoleEncoder returns the ConsoleEncoder with short time format
+func NewConsoleEncoder(color bool) *ConsoleEncoder {
+	return &ConsoleEncoder{TimeFormat: "15:04:05.000", Color: color, FileWidth: 28}
+}
+
+const (
+	colorReset = "\x1b[0m"
+	colorFaint = "\x1b[2m"
+)
+
+// levelColors is the ANSI color of every level, index is Level
+var levelColors = [...]string{
+	PanicLevel: "\x1b[1;35m",
+	FatalLevel: "\x1b[1;35m",
+	ErrorLevel: "\x1b[31m",
+	WarnLevel:  "\x1b[33m",
+	InfoLevel:  "\x1b[32m",
+	DebugLevel: "\x1b[36m",
+	TraceLevel: "\x1b[90m",
+}
+
+func (e *ConsoleEncoder) Encode(buf *bytes.Buffer, r *Record) error {
+	if e.TimeFormat != "" {
+		e.writeColored(buf, colorFaint, r.Time.Format(e.TimeFormat))
+		buf.WriteByte(' ')
+	}
+
+	levelText := r.Level.String()
+	if e.Color && r.Level.IsValid() {
+		e.writeColored(buf, levelColors[r.Level], levelText)
+	} else {
+		buf.WriteString(levelText)
+	}
+	// "ERROR" is the longest name of levels
+	writePadding(buf, 5-len(levelText)+1)
+
+	position := shortFilePath(r.File) + ":" + strconv.Itoa(r.Line)
+	buf.WriteString(position)
+	writePadding(buf, e.FileWidth-len(position)+1)
+
+	buf.WriteByte('[')
+	if !e.HidePid {
+		buf.WriteString(strconv.Itoa(r.Pid))
+		buf.WriteByte(':')
+	}
+	buf.WriteString(strconv.FormatUint(r.GoroutineID, 10))
+	buf.WriteString("] ")
+	buf.WriteString(strings.TrimSuffix(r.Message, "\n"))
+
+	for _, f := range r.Fields {
+		buf.WriteByte(' ')
+		e.writeColored(buf, colorFaint, f.Key+"=")
+		buf.WriteString(formatFieldValue(f.Value))
+	}
+	buf.WriteByte('\n')
+	return nil
+}
+
+func (e *ConsoleEncoder) writeColored(buf *bytes.Buffer, color string, text string) {
+	if !e.Color {
+		buf.WriteString(text)
+		return
+	}
+	buf.WriteString(color)
+	buf.WriteString(text)
+	buf.WriteString(colorReset)
+}
+
+func writePadding(buf *bytes.Buffer, count int) {
+	if count < 1 {
+		count = 1
+	}
+	for i := 0; i < count; i++ {
+		buf.WriteByte(' ')
+	}
+}
+
+// shortFilePath keeps the last directory and the file name, example: "debugutil/verify.go"
+func shortFilePath(file string) string {
+	dir, base := path.Split(file)
+	if dir = strings.TrimSuffix(dir, "/"); dir == "" {
+		return base
+	}
+	return path.Base(dir) + "/" + base
+}
+
+// IsTerminal returns whether the file is a terminal(character device), example: IsTerminal(os.Stderr)
+func IsTerminal(f *os.File) bool {
+	if f == nil {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0 && os.Getenv("TERM") != "dumb"
+}
+
+// EnableConsole sets ConsoleEncoder to the default WriterSink when stderr is a terminal and not in CI(env CI is set),
+// returns whether it's enabled, so the logs in CI or redirected to file are still plain text.
+// the color is disabled by NO_COLOR.
+//
+// Notice: it only checks os.Stderr, so it's not meaningful when the output is changed by SetOutput.
+func EnableConsole() bool {
+	if os.Getenv("CI") != "" || !IsTerminal(os.Stderr) {
+		return false
+	}
+	SetEncoder(NewConsoleEncoder(os.Getenv(EnvNoColor) == ""))
+	return true
+}
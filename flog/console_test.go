@@ -0,0 +1,52 @@
+package flog
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConsoleEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	encoder := NewConsoleEncoder(false)
+	_ = encoder.Encode(&buf, newTestRecord())
+	expected := `07:08:09.123 WARN  debugutil/verify.go:42       [100:7] verify fail: "not exist" upload_id="u 1" msg=3 err=eof` + "\n"
+	if buf.String() != expected {
+		t.Errorf("console=%s\nexpected=%s", buf.String(), expected)
+	}
+
+	buf.Reset()
+	encoder.HidePid, encoder.TimeFormat, encoder.FileWidth = true, "", 0
+	record := newTestRecord()
+	record.Level, record.File, record.Fields = ErrorLevel, "main.go", nil
+	_ = encoder.Encode(&buf, record)
+	expected = `ERROR main.go:42 [7] verify fail: "not exist"` + "\n"
+	if buf.String() != expected {
+		t.Errorf("console=%s\nexpected=%s", buf.String(), expected)
+	}
+
+	buf.Reset()
+	encoder.Color = true
+	_ = encoder.Encode(&buf, record)
+	expected = "\x1b[31mERROR\x1b[0m main.go:42 [7] verify fail: \"not exist\"\n"
+	if buf.String() != expected {
+		t.Errorf("console=%q\nexpected=%q", buf.String(), expected)
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if IsTerminal(f) || IsTerminal(nil) {
+		t.Errorf("regular file is not terminal")
+	}
+
+	t.Setenv("CI", "true")
+	if EnableConsole() {
+		t.Errorf("console should not be enabled in CI")
+	}
+}
@@ -0,0 +1,158 @@
+package flog
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+type contextKey int
+
+const (
+	loggerContextKey contextKey = iota
+	traceIDContextKey
+	spanIDContextKey
+)
+
+// the field names of the builtin context keys
+const (
+	TraceIDField = "trace_id"
+	SpanIDField  = "span_id"
+)
+
+// registeredKey is the context key whose value is output as field
+type registeredKey struct {
+	key       any
+	fieldName string
+}
+
+var (
+	_contextKeysMu sync.Mutex
+	_contextKeys   atomic.Value // []registeredKey, copy on write
+)
+
+func init() {
+	_contextKeys.Store([]registeredKey{
+		{key: traceIDContextKey, fieldName: TraceIDField},
+		{key: spanIDContextKey, fieldName: SpanIDField},
+	})
+}
+
+// RegisterContextKey registers the user defined context key, the value of ctx.Value(key) is output as the field
+// fieldName in every record logged by XxxCtxf or the logger from FromContext, example:
+//
+//	flog.RegisterContextKey(requestIDKey{}, "request_id")
+//
+// register the same key again will replace the field name.
+func RegisterContextKey(key any, fieldName string) {
+	_contextKeysMu.Lock()
+	defer _contextKeysMu.Unlock()
+
+	oldKeys := _contextKeys.Load().([]registeredKey)
+	newKeys := make([]registeredKey, 0, len(oldKeys)+1)
+	for _, k := range oldKeys {
+		if k.key != key {
+			newKeys = append(newKeys, k)
+		}
+	}
+	_contextKeys.Store(append(newKeys, registeredKey{key: key, fieldName: fieldName}))
+}
+
+// ContextWithTraceID returns the context with trace id, which is output as "trace_id" field
+func ContextWithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceIDContextKey, traceID)
+}
+
+// ContextWithSpanID returns the context with span id, which is output as "span_id" field
+func ContextWithSpanID(ctx context.Context, spanID string) context.Context {
+	return context.WithValue(ctx, spanIDContextKey, spanID)
+}
+
+// TraceIDFromContext returns the trace id set by ContextWithTraceID
+func TraceIDFromContext(ctx context.Context) string {
+	traceID, _ := ctx.Value(traceIDContextKey).(string)
+	return traceID
+}
+
+// NewContext returns the context which carries the logger, then FromContext returns it
+func NewContext(ctx context.Context, logger ILoggerEx) context.Context {
+	return context.WithValue(ctx, loggerContextKey, logger)
+}
+
+// loggerFromContext returns the logger set by NewContext, or the current logger
+func loggerFromContext(ctx context.Context) ILoggerEx {
+	if ctx != nil {
+		if logger, ok := ctx.Value(loggerContextKey).(ILoggerEx); ok && logger != nil {
+			return logger
+		}
+	}
+	return curLogger()
+}
+
+// contextFields returns the fields from the registered context keys
+func contextFields(ctx context.Context) []Field {
+	if ctx == nil {
+		return nil
+	}
+	var fields []Field
+	for _, k := range _contextKeys.Load().([]registeredKey) {
+		if value := ctx.Value(k.key); value != nil {
+			fields = append(fields, Field{Key: k.fieldName, Value: value})
+		}
+	}
+	return fields
+}
+
+func withContextFields(ctx context.Context, logger ILoggerEx) ILoggerEx {
+	if fields := contextFields(ctx); len(fields) > 0 {
+		return logger.With(fieldsToKeyValues(fields)...)
+	}
+	return logger
+}
+
+// FromContext returns the logger set by NewContext(or the current logger), with the fields from the registered
+// context keys(trace id, span id, and user defined keys)
+func FromContext(ctx context.Context) ILoggerEx {
+	return withContextFields(ctx, loggerFromContext(ctx))
+}
+
+// logCtxWithPosf is the common entry of XxxCtxf functions, which are called by user directly, so skip is 3.
+// the context fields are only collected when the level is enabled.
+func logCtxWithPosf(ctx context.Context, level Level, format string, args ...any) {
+	l := loggerFromContext(ctx)
+	if !isEnabledAt(l.GetLevel(), level, 3) {
+		return
+	}
+	fileName, lineNo, funName := GetCallStackInfo(3)
+	outputWithPosf(withContextFields(ctx, l), level, fileName, lineNo, funName, format, args...)
+}
+
+func TraceCtxf(ctx context.Context, format string, args ...any) {
+	logCtxWithPosf(ctx, TraceLevel, format, args...)
+}
+
+func DebugCtxf(ctx context.Context, format string, args ...any) {
+	logCtxWithPosf(ctx, DebugLevel, format, args...)
+}
+
+func InfoCtxf(ctx context.Context, format string, args ...any) {
+	logCtxWithPosf(ctx, InfoLevel, format, args...)
+}
+
+func WarnCtxf(ctx context.Context, format string, args ...any) {
+	logCtxWithPosf(ctx, WarnLevel, format, args...)
+}
+
+func ErrorCtxf(ctx context.Context, format string, args ...any) {
+	logCtxWithPosf(ctx, ErrorLevel, format, args...)
+}
+
+// FatalCtxf logs and then calls `os.Exit(1)`
+func FatalCtxf(ctx context.Context, format string, args ...any) {
+	logCtxWithPosf(ctx, FatalLevel, format, args...)
+}
+
+// PanicCtxf logs and then calls panic with the message
+func PanicCtxf(ctx context.Context, format string, args ...any) {
+	logCtxWithPosf(ctx, PanicLevel, format, args...)
+}
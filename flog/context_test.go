@@ -0,0 +1,43 @@
+package flog
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+type requestIDKey struct{}
+
+func TestContextLogging(t *testing.T) {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	defer SetOutput(stdLogWriter{})
+
+	RegisterContextKey(requestIDKey{}, "request_id")
+	ctx := ContextWithTraceID(context.Background(), "t1")
+	ctx = context.WithValue(ctx, requestIDKey{}, "r1")
+	if TraceIDFromContext(ctx) != "t1" {
+		t.Errorf("wrong trace id")
+	}
+
+	WarnCtxf(ctx, "upload %s", "start")
+	ctx = NewContext(ctx, With("upload_id", "u1"))
+	FromContext(ctx).Warnf("upload finished")
+	WarnCtxf(context.Background(), "no context fields")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("wrong lines: %q", lines)
+	}
+	expected := []string{
+		"[trace_id=t1 request_id=r1] upload start",
+		"[upload_id=u1 trace_id=t1 request_id=r1] upload finished",
+		"[none] no context fields",
+	}
+	for i, line := range lines {
+		if !strings.Contains(line, expected[i]) || !strings.Contains(line, "context_test.go") {
+			t.Errorf("line %d: %s, expected %s", i, line, expected[i])
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package flog
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const queueSegmentSuffix = ".q"
+
+// queueSegment is one file of diskQueue, size and count are the unsent part
+type queueSegment struct {
+	seq   uint64
+	size  int64
+	count int
+}
+
+// diskQueue is the FIFO of the messages(end with '\n') in the segment files("00000001.q", ...) of dir,
+// the oldest segment is dropped when the total size exceeds maxBytes.
+// the messages left by the previous process are loaded when open. it's not safe for concurrent use.
+type diskQueue struct {
+	dir         string
+	maxBytes    int64
+	segmentSize int64
+
+	segments []*queueSegment // the oldest first
+	writer   *os.File        // appends to the last segment, nil means the next push creates new segment
+	nextSeq  uint64
+	size     int64
+	count    int
+	// first is the index of the oldest message since open, increased by pop and drop
+	first uint64
+
+	// head is the content of the first segment loaded by peek, headPos is the offset of the next message
+	head    []byte
+	headPos int
+}
+
+func openDiskQueue(dir string, maxBytes int64) (*diskQueue, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	q := &diskQueue{dir: dir, maxBytes: maxBytes, segmentSize: maxBytes / 8, nextSeq: 1}
+	if q.segmentSize < 4096 {
+		q.segmentSize = 4096
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, "*"+queueSegmentSuffix))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range matches {
+		seq, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(file), queueSegmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		// the last line may be incomplete if the process crashed while writing, it's ignored
+		complete := bytes.LastIndexByte(data, '\n') + 1
+		q.segments = append(q.segments, &queueSegment{
+			seq:   seq,
+			size:  int64(complete),
+			count: bytes.Count(data[:complete], []byte{'\n'}),
+		})
+	}
+	sort.Slice(q.segments, func(i, j int) bool { return q.segments[i].seq < q.segments[j].seq })
+	for _, seg := range q.segments {
+		q.size += seg.size
+		q.count += seg.count
+		q.nextSeq = seg.seq + 1
+	}
+	return q, nil
+}
+
+func (q *diskQueue) segmentPath(seq uint64) string {
+	return filepath.Join(q.dir, fmt.Sprintf("%08d%s", seq, queueSegmentSuffix))
+}
+
+// push appends the message, returns the count of the old messages dropped because the queue is full
+func (q *diskQueue) push(msg []byte) (int, error) {
+	if int64(len(msg)) > q.maxBytes {
+		return 1, fmt.Errorf("flog: message size %d exceeds the queue size %d", len(msg), q.maxBytes)
+	}
+	dropped := 0
+	for q.size+int64(len(msg)) > q.maxBytes && len(q.segments) > 0 {
+		dropped += q.segments[0].count
+		q.removeHead()
+	}
+
+	last := len(q.segments) - 1
+	if q.writer == nil || q.segments[last].size+int64(len(msg)) > q.segmentSize {
+		if err := q.newSegment(); err != nil {
+			return dropped, err
+		}
+		last = len(q.segments) - 1
+	}
+	if _, err := q.writer.Write(msg); err != nil {
+		return dropped, err
+	}
+	seg := q.segments[last]
+	seg.size += int64(len(msg))
+	seg.count++
+	q.size += int64(len(msg))
+	q.count++
+	return dropped, nil
+}
+
+func (q *diskQueue) newSegment() error {
+	q.closeWriter()
+	file, err := os.OpenFile(q.segmentPath(q.nextSeq), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	q.writer = file
+	q.segments = append(q.segments, &queueSegment{seq: q.nextSeq})
+	q.nextSeq++
+	return nil
+}
+
+// peek returns the oldest message, false if the queue is empty
+func (q *diskQueue) peek() ([]byte, bool, error) {
+	for q.count > 0 {
+		if q.head == nil {
+			if len(q.segments) == 1 {
+				// the segment is being written, the later messages go to the next segment
+				q.closeWriter()
+			}
+			data, err := os.ReadFile(q.segmentPath(q.segments[0].seq))
+			if err != nil {
+				return nil, false, err
+			}
+			q.head, q.headPos = data, 0
+		}
+		if end := bytes.IndexByte(q.head[q.headPos:], '\n'); end >= 0 {
+			return q.head[q.headPos : q.headPos+end+1], true, nil
+		}
+		// should not happen, the file is changed by others
+		q.removeHead()
+	}
+	return nil, false, nil
+}
+
+// peekBatch returns at most limit oldest messages of the first segment and the index of the first one,
+// the messages are valid until the segment is removed
+func (q *diskQueue) peekBatch(limit int) ([][]byte, uint64, error) {
+	if _, ok, err := q.peek(); !ok || err != nil {
+		return nil, q.first, err
+	}
+	var batch [][]byte
+	for pos := q.headPos; len(batch) < limit && len(batch) < q.segments[0].count; {
+		end := bytes.IndexByte(q.head[pos:], '\n')
+		if end < 0 {
+			break
+		}
+		batch = append(batch, q.head[pos:pos+end+1])
+		pos += end + 1
+	}
+	return batch, q.first, nil
+}
+
+// popUntil removes the messages whose index is less than index, the messages may be dropped already
+func (q *diskQueue) popUntil(index uint64) {
+	for q.count > 0 && q.first < index {
+		q.pop()
+	}
+}
+
+// pop removes the message returned by peek
+func (q *diskQueue) pop() {
+	msg, ok, _ := q.peek()
+	if !ok {
+		return
+	}
+	seg := q.segments[0]
+	q.headPos += len(msg)
+	seg.size -= int64(len(msg))
+	seg.count--
+	q.size -= int64(len(msg))
+	q.count--
+	q.first++
+	if seg.count <= 0 {
+		q.removeHead()
+	}
+}
+
+// removeHead deletes the oldest segment with its unsent messages
+func (q *diskQueue) removeHead() {
+	seg := q.segments[0]
+	if len(q.segments) == 1 {
+		q.closeWriter()
+	}
+	_ = os.Remove(q.segmentPath(seg.seq))
+	q.size -= seg.size
+	q.count -= seg.count
+	q.first += uint64(seg.count)
+	q.segments = q.segments[1:]
+	q.head, q.headPos = nil, 0
+}
+
+func (q *diskQueue) closeWriter() {
+	if q.writer != nil {
+		_ = q.writer.Close()
+		q.writer = nil
+	}
+}
+
+func (q *diskQueue) close() {
+	q.closeWriter()
+}
@@ -0,0 +1,240 @@
+package flog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// Record is the full information of one log, which is passed to Encoder
+type Record struct {
+	Time        time.Time
+	Level       Level
+	File        string // full path of the source file
+	Line        int
+	Function    string // short function name, example: "Read" or "(*VirtualWriter).Read"
+	Pid         int
+	GoroutineID uint64
+	Message     string
+	Fields      []Field
+}
+
+// Encoder formats the record into buf, the output should end with '\n'
+type Encoder interface {
+	Encode(buf *bytes.Buffer, r *Record) error
+}
+
+// TextEncoder is the default encoder, same layout as before:
+//
+//	2006/01/02 15:04:05 [ file.go:12 ][pid][gid][Level][k=v ...] message
+//
+// the level is "Debug", "Info", "WARN" like before(see {level:legacy} of ParseLayout), and the time is added by
+// go std log(with its flags and prefix) when the default logger writes to it.
+//
+// or the custom Layout, example:
+//
+//	&flog.TextEncoder{Layout: flog.MustParseLayout("{time:rfc3339micro} {level} {file:short}:{line} {func} {msg}")}
+type TextEncoder struct {
+	// TimeFormat is the layout of time.Format, empty means no time(example: the output already has time),
+	// it's ignored when Layout is set
+	TimeFormat string
+	// Layout is the layout of the whole line, nil means the default layout
+	Layout *Layout
+}
+
+// defaultTextLayout is the default layout of TextEncoder after the time
+var defaultTextLayout = MustParseLayout("[ {file}:{line} ][{pid}][{gid}][{level:legacy}][{fields:none}] {msg}")
+
+// defaultTextTimeFormat is same as the default flags of go std log
+const defaultTextTimeFormat = "2006/01/02 15:04:05"
+
+// NewTextEncoder returns the TextEncoder with the same time format as go std log
+func NewTextEncoder() *TextEncoder {
+	return &TextEncoder{TimeFormat: defaultTextTimeFormat}
+}
+
+func (e *TextEncoder) Encode(buf *bytes.Buffer, r *Record) error {
+	layout := e.Layout
+	if layout == nil {
+		if e.TimeFormat != "" {
+			var timeBuf [64]byte
+			buf.Write(r.Time.AppendFormat(timeBuf[:0], e.TimeFormat))
+			buf.WriteByte(' ')
+		}
+		layout = defaultTextLayout
+	}
+	layout.Append(buf, r)
+	if !bytes.HasSuffix(buf.Bytes(), []byte{'\n'}) {
+		buf.WriteByte('\n')
+	}
+	return nil
+}
+
+// JSONEncoder output JSON lines, the fields are in the top level, renamed to "fields.xxx" if conflict
+// with the builtin keys, example:
+//
+//	{"time":"2006-01-02T15:04:05.000000Z07:00","level":"WARN","file":"verify.go","line":12,"func":"Verify","pid":1,"gid":2,"msg":"xxx","k":"v"}
+type JSONEncoder struct {
+	// TimeFormat is the layout of time.Format, default is RFC3339 with microseconds
+	TimeFormat string
+}
+
+func NewJSONEncoder() *JSONEncoder {
+	return &JSONEncoder{TimeFormat: "2006-01-02T15:04:05.000000Z07:00"}
+}
+
+var builtinKeys = map[string]bool{
+	"time": true, "level": true, "file": true, "line": true, "func": true, "pid": true, "gid": true, "msg": true,
+}
+
+func (e *JSONEncoder) Encode(buf *bytes.Buffer, r *Record) error {
+	buf.WriteString(`{"time":`)
+	appendJSONString(buf, r.Time.Format(e.TimeFormat))
+	buf.WriteString(`,"level":`)
+	appendJSONString(buf, r.Level.String())
+	buf.WriteString(`,"file":`)
+	appendJSONString(buf, path.Base(r.File))
+	buf.WriteString(`,"line":`)
+	buf.WriteString(strconv.Itoa(r.Line))
+	buf.WriteString(`,"func":`)
+	appendJSONString(buf, r.Function)
+	buf.WriteString(`,"pid":`)
+	buf.WriteString(strconv.Itoa(r.Pid))
+	buf.WriteString(`,"gid":`)
+	buf.WriteString(strconv.FormatUint(r.GoroutineID, 10))
+	buf.WriteString(`,"msg":`)
+	appendJSONString(buf, r.Message)
+	for _, f := range r.Fields {
+		key := f.Key
+		if builtinKeys[key] {
+			key = "fields." + key
+		}
+		buf.WriteByte(',')
+		appendJSONString(buf, key)
+		buf.WriteByte(':')
+		appendJSONValue(buf, f.Value)
+	}
+	buf.WriteString("}\n")
+	return nil
+}
+
+// appendJSONValue use json.Marshal for the value, error and the value can not marshal are output as string
+func appendJSONValue(buf *bytes.Buffer, value any) {
+	switch v := value.(type) {
+	case string:
+		appendJSONString(buf, v)
+		return
+	case error:
+		appendJSONString(buf, v.Error())
+		return
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		appendJSONString(buf, fmt.Sprint(value))
+		return
+	}
+	buf.Write(data)
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString writes the quoted json string, without the HTML escape of json.Marshal
+func appendJSONString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	start := 0
+	for i := 0; i < len(s); {
+		b := s[i]
+		if b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' {
+				i++
+				continue
+			}
+			buf.WriteString(s[start:i])
+			switch b {
+			case '"', '\\':
+				buf.WriteByte('\\')
+				buf.WriteByte(b)
+			case '\n':
+				buf.WriteString(`\n`)
+			case '\r':
+				buf.WriteString(`\r`)
+			case '\t':
+				buf.WriteString(`\t`)
+			default:
+				buf.WriteString(`\u00`)
+				buf.WriteByte(hexDigits[b>>4])
+				buf.WriteByte(hexDigits[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		c, size := utf8.DecodeRuneInString(s[i:])
+		if c == utf8.RuneError && size == 1 {
+			buf.WriteString(s[start:i])
+			buf.WriteString(`\ufffd`)
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	buf.WriteString(s[start:])
+	buf.WriteByte('"')
+}
+
+// LogfmtEncoder output logfmt(https://brandur.org/logfmt) lines, example:
+//
+//	time=2006-01-02T15:04:05.000000Z07:00 level=WARN file=verify.go line=12 func=Verify pid=1 gid=2 msg="xxx" k=v
+type LogfmtEncoder struct {
+	// TimeFormat is the layout of time.Format, default is RFC3339 with microseconds
+	TimeFormat string
+}
+
+func NewLogfmtEncoder() *LogfmtEncoder {
+	return &LogfmtEncoder{TimeFormat: "2006-01-02T15:04:05.000000Z07:00"}
+}
+
+func (e *LogfmtEncoder) Encode(buf *bytes.Buffer, r *Record) error {
+	buf.WriteString("time=")
+	buf.WriteString(r.Time.Format(e.TimeFormat))
+	buf.WriteString(" level=")
+	buf.WriteString(r.Level.String())
+	buf.WriteString(" file=")
+	appendLogfmtValue(buf, path.Base(r.File))
+	buf.WriteString(" line=")
+	buf.WriteString(strconv.Itoa(r.Line))
+	buf.WriteString(" func=")
+	appendLogfmtValue(buf, r.Function)
+	buf.WriteString(" pid=")
+	buf.WriteString(strconv.Itoa(r.Pid))
+	buf.WriteString(" gid=")
+	buf.WriteString(strconv.FormatUint(r.GoroutineID, 10))
+	buf.WriteString(" msg=")
+	appendLogfmtValue(buf, r.Message)
+	for _, f := range r.Fields {
+		buf.WriteByte(' ')
+		buf.WriteString(f.Key)
+		buf.WriteByte('=')
+		appendLogfmtValue(buf, fieldValueString(f.Value))
+	}
+	buf.WriteByte('\n')
+	return nil
+}
+
+func appendLogfmtValue(buf *bytes.Buffer, s string) {
+	needQuote := s == ""
+	for i := 0; i < len(s) && !needQuote; i++ {
+		b := s[i]
+		needQuote = b <= ' ' || b == '=' || b == '"' || b == '\\' || b >= utf8.RuneSelf
+	}
+	if needQuote {
+		buf.WriteString(strconv.Quote(s))
+	} else {
+		buf.WriteString(s)
+	}
+}
@@ -0,0 +1,86 @@
+package flog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestRecord() *Record {
+	return &Record{
+		Time:        time.Date(2023, 5, 6, 7, 8, 9, 123456000, time.UTC),
+		Level:       WarnLevel,
+		File:        "/src/debugutil/verify.go",
+		Line:        42,
+		Function:    "checkAndHandleError",
+		Pid:         100,
+		GoroutineID: 7,
+		Message:     `verify fail: "not exist"`,
+		Fields:      []Field{{"upload_id", "u 1"}, {"msg", 3}, {"err", errors.New("eof")}},
+	}
+}
+
+func TestTextEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	_ = NewTextEncoder().Encode(&buf, newTestRecord())
+	expected := `2023/05/06 07:08:09 [ verify.go:42 ][100][7][WARN][upload_id="u 1" msg=3 err=eof] verify fail: "not exist"` + "\n"
+	if buf.String() != expected {
+		t.Errorf("text=%s\nexpected=%s", buf.String(), expected)
+	}
+}
+
+func TestJSONEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	_ = NewJSONEncoder().Encode(&buf, newTestRecord())
+
+	var result map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatalf("invalid json %s, err=%v", buf.String(), err)
+	}
+	expected := map[string]any{
+		"time": "2023-05-06T07:08:09.123456Z", "level": "WARN", "file": "verify.go", "line": 42.0,
+		"func": "checkAndHandleError", "pid": 100.0, "gid": 7.0, "msg": `verify fail: "not exist"`,
+		"upload_id": "u 1", "fields.msg": 3.0, "err": "eof",
+	}
+	for k, v := range expected {
+		if result[k] != v {
+			t.Errorf("json key %s: %v != %v", k, result[k], v)
+		}
+	}
+	if !strings.HasSuffix(buf.String(), "}\n") {
+		t.Errorf("json should end with new line")
+	}
+}
+
+func TestLogfmtEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	_ = NewLogfmtEncoder().Encode(&buf, newTestRecord())
+	expected := `time=2023-05-06T07:08:09.123456Z level=WARN file=verify.go line=42 func=checkAndHandleError ` +
+		`pid=100 gid=7 msg="verify fail: \"not exist\"" upload_id="u 1" msg=3 err=eof` + "\n"
+	if buf.String() != expected {
+		t.Errorf("logfmt=%s\nexpected=%s", buf.String(), expected)
+	}
+}
+
+func TestSetEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	SetEncoder(NewJSONEncoder())
+	defer func() {
+		SetEncoder(NewTextEncoder())
+		SetOutput(stdLogWriter{})
+	}()
+
+	With("part", 1).Warnf("hello %s", "json")
+	var result map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatalf("invalid json %s, err=%v", buf.String(), err)
+	}
+	if result["msg"] != "hello json" || result["part"] != 1.0 || result["func"] != "TestSetEncoder" ||
+		result["file"] != "encoder_test.go" {
+		t.Errorf("wrong json: %s", buf.String())
+	}
+}
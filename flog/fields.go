@@ -0,0 +1,128 @@
+package flog
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Field is a structured key/value carried by the logger, and output in every record
+type Field struct {
+	Key   string
+	Value any
+}
+
+// Fields is the map style of fields, used by WithFields
+type Fields map[string]any
+
+// FieldLogger is an optional interface for the simple ILogger created by LoggerFactory,
+// so it can receive the structured fields from `flog.With`/`flog.WithFields`.
+//
+// WithFieldList should return a child logger which carries both the parent's fields and the new fields.
+// if not implemented, the fields are formatted as "[k=v ...] " prefix of the message.
+type FieldLogger interface {
+	ILogger
+	WithFieldList(fields []Field) ILogger
+}
+
+const badKey = "!BADKEY"
+
+// keyValuesToFields converts the key/value pairs(like slog) to fields,
+// a key which is not string is formatted, a key without value is saved as value of "!BADKEY"
+func keyValuesToFields(keyValues []any) []Field {
+	fields := make([]Field, 0, (len(keyValues)+1)/2)
+	for i := 0; i < len(keyValues); i += 2 {
+		if i+1 >= len(keyValues) {
+			fields = append(fields, Field{Key: badKey, Value: keyValues[i]})
+			break
+		}
+		key, ok := keyValues[i].(string)
+		if !ok {
+			key = fmt.Sprint(keyValues[i])
+		}
+		fields = append(fields, Field{Key: key, Value: keyValues[i+1]})
+	}
+	return fields
+}
+
+// fieldsToKeyValues converts the fields to key/value pairs, used by ILoggerEx.With
+func fieldsToKeyValues(fields []Field) []any {
+	keyValues := make([]any, 0, len(fields)*2)
+	for _, f := range fields {
+		keyValues = append(keyValues, f.Key, f.Value)
+	}
+	return keyValues
+}
+
+// mapToFields converts Fields to slice sorted by key, so the output is stable
+func mapToFields(fields Fields) []Field {
+	result := make([]Field, 0, len(fields))
+	for k, v := range fields {
+		result = append(result, Field{Key: k, Value: v})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+// mergeFields returns a new slice with parent's fields and the new fields,
+// never modify the parent's slice which may be shared by other child loggers.
+func mergeFields(parent []Field, fields []Field) []Field {
+	if len(fields) == 0 {
+		return parent
+	}
+	result := make([]Field, 0, len(parent)+len(fields))
+	result = append(result, parent...)
+	return append(result, fields...)
+}
+
+// fieldValueString returns the raw text of field value
+func fieldValueString(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// formatFieldValue returns the text of field value, quote it if contains space or special chars
+func formatFieldValue(value any) string {
+	s := fieldValueString(value)
+	if s == "" || strings.ContainsAny(s, " =[]\"\t\r\n") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// formatFieldsText returns "k1=v1 k2=v2", used by text output
+func formatFieldsText(fields []Field) string {
+	var sb strings.Builder
+	for i, f := range fields {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(f.Key)
+		sb.WriteByte('=')
+		sb.WriteString(formatFieldValue(f.Value))
+	}
+	return sb.String()
+}
+
+// With returns a child logger of current logger which carries the key/value pairs, example:
+//
+//	flog.With("upload_id", id, "part", index).Infof("upload part finished")
+func With(keyValues ...any) ILoggerEx {
+	return curLogger().With(keyValues...)
+}
+
+// WithFields returns a child logger of current logger which carries the fields
+func WithFields(fields Fields) ILoggerEx {
+	return curLogger().WithFields(fields)
+}
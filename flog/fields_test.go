@@ -0,0 +1,82 @@
+package flog
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+type simpleFieldLogger struct {
+	simpleLogger
+	fields []Field
+}
+
+func (s *simpleFieldLogger) WithFieldList(fields []Field) ILogger {
+	return &simpleFieldLogger{fields: mergeFields(s.fields, fields)}
+}
+
+func TestKeyValuesToFields(t *testing.T) {
+	fields := keyValuesToFields([]any{"a", 1, 2, "b", "c"})
+	expected := []Field{{"a", 1}, {"2", "b"}, {badKey, "c"}}
+	if fmt.Sprint(fields) != fmt.Sprint(expected) {
+		t.Errorf("fields=%v, expected=%v", fields, expected)
+	}
+
+	text := formatFieldsText(mapToFields(Fields{"name": "a b", "id": 10}))
+	if text != `id=10 name="a b"` {
+		t.Errorf("wrong fields text: %s", text)
+	}
+}
+
+func TestDefaultLoggerWith(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	logger := With("upload_id", "u1")
+	logger.With("part", 2).Warnf("part %d uploaded", 2)
+	logger.Warnf("upload finished")
+	Warnf("no fields")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("wrong output lines: %q", lines)
+	}
+	if !strings.Contains(lines[0], "[upload_id=u1 part=2] part 2 uploaded") {
+		t.Errorf("wrong line 0: %s", lines[0])
+	}
+	if !strings.Contains(lines[1], "[upload_id=u1] upload finished") {
+		t.Errorf("wrong line 1: %s", lines[1])
+	}
+	if !strings.Contains(lines[2], "[none] no fields") {
+		t.Errorf("wrong line 2: %s", lines[2])
+	}
+}
+
+func TestCompatLoggerWith(t *testing.T) {
+	oldLogger := curLogger()
+	defer SetLogger(oldLogger)
+
+	simple := &simpleLogger{}
+	SetLoggerFactory(func() ILogger {
+		return simple
+	})
+	With("rate", "100%").Infof("value=%d", 1)
+	if len(simple.lines) != 1 || simple.lines[0] != "I:[rate=100%] value=1" {
+		t.Errorf("wrong lines: %q", simple.lines)
+	}
+
+	fieldLogger := &simpleFieldLogger{}
+	SetLoggerFactory(func() ILogger {
+		return fieldLogger
+	})
+	child := With("a", 1).With("b", 2)
+	child.Infof("hello")
+	inner := child.(*compatLogger).ILogger.(*simpleFieldLogger)
+	if fmt.Sprint(inner.fields) != "[{a 1} {b 2}]" || len(inner.lines) != 1 || inner.lines[0] != "I:hello" {
+		t.Errorf("wrong field logger: fields=%v, lines=%q", inner.fields, inner.lines)
+	}
+}
@@ -0,0 +1,130 @@
+// Package flogtest provides the logger for the unit tests which use flog.
+//
+// it's not in package flog, so the programs using flog don't link the testing package(and its flags).
+package flogtest
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/fishjam/go-library/flog"
+)
+
+// testLayout puts the real position of the log first, testing prints the position of t.Log before it
+var testLayout = flog.MustParseLayout("{file:short}:{line}: [{level}][{fields}] {msg}")
+
+// TestLogger is the logger for unit test, it outputs the records by t.Log(so the output of the test is not mixed
+// with others), and keeps the records for assertion, example:
+//
+//	tl := flogtest.NewTestLogger(t)
+//	debugutil.Verify(err == nil, ...)
+//	if tl.Count(flog.WarnLevel, "verify fail") != 1 { t.Errorf(...) }
+//
+// it's installed as the current logger until the test finishes, the parallel tests share one current logger,
+// so they should use the returned logger(or NewContext) directly instead of the package-level functions.
+//
+// Notice: testing prints the position of t.Log, the real position of the log is at the beginning of the message.
+type TestLogger struct {
+	flog.ILoggerEx
+	sink *testSink
+	prev flog.ILoggerEx // the previous current logger, guarded by _testLoggersMu
+}
+
+// testSink is the sink of TestLogger and its child loggers
+type testSink struct {
+	mu      sync.Mutex
+	t       testing.TB
+	encoder *flog.TextEncoder
+	records []flog.Record
+	done    bool // the test finished, t.Log panics after that
+}
+
+var (
+	_testLoggersMu sync.Mutex
+	// _testLoggers are the installed test loggers whose test is not finished
+	_testLoggers []*TestLogger
+)
+
+// NewTestLogger returns the TestLogger(level is TraceLevel) and sets it as the current logger,
+// the previous logger is restored by t.Cleanup.
+func NewTestLogger(t testing.TB) *TestLogger {
+	sink := &testSink{t: t, encoder: &flog.TextEncoder{Layout: testLayout}}
+	tl := &TestLogger{
+		ILoggerEx: flog.NewLogger(flog.TraceLevel, sink),
+		sink:      sink,
+	}
+
+	_testLoggersMu.Lock()
+	_testLoggers = append(_testLoggers, tl)
+	tl.prev = flog.SetLogger(tl.ILoggerEx)
+	_testLoggersMu.Unlock()
+
+	t.Cleanup(func() {
+		_testLoggersMu.Lock()
+		defer _testLoggersMu.Unlock()
+
+		sink.mu.Lock()
+		sink.done = true
+		sink.mu.Unlock()
+
+		// the test loggers installed later(parallel tests) restore tl.prev instead of the finished tl
+		for i := 0; i < len(_testLoggers); i++ {
+			// the builtin logger is a pointer, the comparison never panics even if prev is not comparable
+			if other := _testLoggers[i]; other == tl {
+				_testLoggers = append(_testLoggers[:i], _testLoggers[i+1:]...)
+				i--
+			} else if other.prev == tl.ILoggerEx {
+				other.prev = tl.prev
+			}
+		}
+		// another test logger may be installed later, only restore when it's still current
+		if flog.CurrentLogger() == tl.ILoggerEx {
+			flog.SetLogger(tl.prev)
+		}
+	})
+	return tl
+}
+
+func (s *testSink) WriteRecord(r *flog.Record) error {
+	var buf bytes.Buffer
+	_ = s.encoder.Encode(&buf, r)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record := *r
+	record.Fields = append([]flog.Field(nil), r.Fields...)
+	s.records = append(s.records, record)
+	if !s.done {
+		s.t.Log(strings.TrimSuffix(buf.String(), "\n"))
+	}
+	return nil
+}
+
+// Records returns the copy of all the records, include the records of child loggers
+func (tl *TestLogger) Records() []flog.Record {
+	tl.sink.mu.Lock()
+	defer tl.sink.mu.Unlock()
+	return append([]flog.Record(nil), tl.sink.records...)
+}
+
+// Count returns the count of records at level whose message contains substr, empty substr matches all
+func (tl *TestLogger) Count(level flog.Level, substr string) int {
+	tl.sink.mu.Lock()
+	defer tl.sink.mu.Unlock()
+	count := 0
+	for i := range tl.sink.records {
+		if tl.sink.records[i].Level == level && strings.Contains(tl.sink.records[i].Message, substr) {
+			count++
+		}
+	}
+	return count
+}
+
+// Reset clears the kept records
+func (tl *TestLogger) Reset() {
+	tl.sink.mu.Lock()
+	defer tl.sink.mu.Unlock()
+	tl.sink.records = nil
+}
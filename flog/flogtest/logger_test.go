@@ -0,0 +1,104 @@
+package flogtest
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/fishjam/go-library/flog"
+)
+
+func TestNewTestLogger(t *testing.T) {
+	oldLogger := flog.CurrentLogger()
+	t.Run("logs", func(t *testing.T) {
+		tl := NewTestLogger(t)
+		if flog.CurrentLogger() != tl.ILoggerEx {
+			t.Fatalf("test logger should be installed")
+		}
+		flog.Warnf("verify fail: %d", 1)
+		flog.With("k", "v").Warnf("verify fail: %d", 2)
+		flog.Debugf("debug")
+		tl.Errorf("error")
+
+		if count := tl.Count(flog.WarnLevel, "verify fail"); count != 2 {
+			t.Errorf("wrong warn count %d", count)
+		}
+		records := tl.Records()
+		if len(records) != 4 || !strings.HasSuffix(records[0].File, "logger_test.go") {
+			t.Fatalf("wrong records %v", records)
+		}
+		var buf bytes.Buffer
+		testLayout.Append(&buf, &records[0])
+		if !strings.HasPrefix(buf.String(), "flogtest/logger_test.go:") {
+			t.Errorf("the message should start with the position: %s", buf.String())
+		}
+		if len(records[1].Fields) != 1 || records[1].Fields[0].Key != "k" {
+			t.Errorf("wrong fields %v", records[1].Fields)
+		}
+		tl.Reset()
+		if len(tl.Records()) != 0 {
+			t.Errorf("records should be reset")
+		}
+	})
+	if flog.CurrentLogger() != oldLogger {
+		t.Errorf("logger should be restored")
+	}
+}
+
+func TestNewTestLoggerNested(t *testing.T) {
+	oldLogger := flog.CurrentLogger()
+	var outer, inner *TestLogger
+	t.Run("outer", func(t *testing.T) {
+		outer = NewTestLogger(t)
+		t.Run("inner", func(t *testing.T) {
+			inner = NewTestLogger(t)
+			flog.Infof("inner")
+		})
+		if flog.CurrentLogger() != outer.ILoggerEx {
+			t.Errorf("outer logger should be restored")
+		}
+		flog.Infof("outer")
+	})
+	if flog.CurrentLogger() != oldLogger {
+		t.Errorf("logger should be restored")
+	}
+	if outer.Count(flog.InfoLevel, "") != 1 || inner.Count(flog.InfoLevel, "inner") != 1 {
+		t.Errorf("wrong records, outer=%v, inner=%v", outer.Records(), inner.Records())
+	}
+}
+
+// cleanupTB runs the cleanups by cleanup, so the tests can finish in any order
+type cleanupTB struct {
+	testing.TB
+	cleanups []func()
+}
+
+func (tb *cleanupTB) Cleanup(f func()) {
+	tb.cleanups = append(tb.cleanups, f)
+}
+
+func (tb *cleanupTB) cleanup() {
+	for i := len(tb.cleanups) - 1; i >= 0; i-- {
+		tb.cleanups[i]()
+	}
+}
+
+func TestNewTestLoggerFinishOutOfOrder(t *testing.T) {
+	oldLogger := flog.CurrentLogger()
+	first, second := &cleanupTB{TB: t}, &cleanupTB{TB: t}
+	NewTestLogger(first)
+	secondLogger := NewTestLogger(second)
+
+	// the first finishes before the second, like the parallel tests
+	first.cleanup()
+	if flog.CurrentLogger() != secondLogger.ILoggerEx {
+		t.Errorf("the current logger should not be changed")
+	}
+	second.cleanup()
+	if flog.CurrentLogger() != oldLogger {
+		t.Errorf("logger should be restored")
+	}
+	if len(_testLoggers) != 0 {
+		t.Errorf("the finished test loggers should be removed, %d left", len(_testLoggers))
+	}
+}
@@ -9,11 +9,25 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"unsafe"
 )
 
 var goroutineSpace = []byte("goroutine ")
 
+// goidOffset is the offset of goid in runtime.g, 0 means not supported
+var goidOffset = calibrateGoidOffset()
+
+// GetGoroutineID returns the id of current goroutine, it reads the goid from runtime.g directly when the
+// offset is calibrated at init(amd64 and arm64), otherwise parse it from runtime.Stack.
 func GetGoroutineID() uint64 {
+	if goidOffset != 0 {
+		return *(*uint64)(unsafe.Add(getg(), goidOffset))
+	}
+	return parseGoroutineID()
+}
+
+// parseGoroutineID parses the goroutine id from the first line of runtime.Stack, it's slow but always work
+func parseGoroutineID() uint64 {
 	bp := littleBuf.Get().(*[]byte)
 	defer littleBuf.Put(bp)
 	b := *bp
@@ -32,17 +46,21 @@ func GetGoroutineID() uint64 {
 	return n
 }
 
-// GetCallStackInfo return fileName, lineNo, funName
+// GetCallStackInfo return fileName, lineNo, funName, the frame of pc is cached(see GetCallStack)
 func GetCallStackInfo(skip int) (string, int, string) {
-	var funName string
-	pc, fileName, lineNo, ok := runtime.Caller(skip)
-	if ok {
-		funName = strings.TrimPrefix(filepath.Ext(runtime.FuncForPC(pc).Name()), ".")
-	} else {
-		funName = "<Unknown>"
-		fileName, lineNo = "<Unknown>", -1
+	var pcs [1]uintptr
+	// +1 for runtime.Callers itself, same as runtime.Caller(skip)
+	if runtime.Callers(skip+1, pcs[:]) > 0 {
+		if frames := framesOfPC(pcs[0]); len(frames) > 0 {
+			return frames[0].File, frames[0].Line, frames[0].ShortFunction
+		}
 	}
-	return fileName, lineNo, funName
+	return "<Unknown>", -1, "<Unknown>"
+}
+
+// shortFuncName returns the short name of full function name, example: "github.com/fishjam/go-library/debugutil.Verify" => "Verify"
+func shortFuncName(name string) string {
+	return strings.TrimPrefix(filepath.Ext(name), ".")
 }
 
 var littleBuf = sync.Pool{
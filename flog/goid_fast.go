@@ -0,0 +1,43 @@
+//go:build gc && (amd64 || arm64)
+
+package flog
+
+import (
+	"unsafe"
+)
+
+// getg returns the pointer of runtime.g of current goroutine, implemented in assembly
+func getg() unsafe.Pointer
+
+// maxGoidOffset is the max offset to search goid in runtime.g, it's smaller than the size of runtime.g
+// in all the go versions, so never read out of the object.
+const maxGoidOffset = 320
+
+// calibrateGoidOffset searches the offset of goid in runtime.g by compare with the parsed goroutine id,
+// and verifies it in other goroutines(different ids), so a chance match value is excluded.
+// returns 0 if not found, then GetGoroutineID falls back to parse runtime.Stack.
+func calibrateGoidOffset() uintptr {
+	g := getg()
+	id := parseGoroutineID()
+	for offset := uintptr(8); offset < maxGoidOffset; offset += 8 {
+		if *(*uint64)(unsafe.Add(g, offset)) == id && verifyGoidOffset(offset, 4) {
+			return offset
+		}
+	}
+	return 0
+}
+
+// verifyGoidOffset checks the offset in count new goroutines
+func verifyGoidOffset(offset uintptr, count int) bool {
+	results := make(chan bool, count)
+	for i := 0; i < count; i++ {
+		go func() {
+			results <- *(*uint64)(unsafe.Add(getg(), offset)) == parseGoroutineID()
+		}()
+	}
+	ok := true
+	for i := 0; i < count; i++ {
+		ok = <-results && ok
+	}
+	return ok
+}
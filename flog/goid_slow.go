@@ -0,0 +1,16 @@
+//go:build !gc || !(amd64 || arm64)
+
+package flog
+
+import (
+	"unsafe"
+)
+
+// getg is not supported on this arch, GetGoroutineID always parses runtime.Stack
+func getg() unsafe.Pointer {
+	return nil
+}
+
+func calibrateGoidOffset() uintptr {
+	return 0
+}
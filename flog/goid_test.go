@@ -0,0 +1,79 @@
+package flog
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestGoroutineIDFastPath(t *testing.T) {
+	if (runtime.GOARCH == "amd64" || runtime.GOARCH == "arm64") && goidOffset == 0 {
+		t.Errorf("goid offset should be calibrated on %s", runtime.GOARCH)
+	}
+	t.Logf("goid offset=%d", goidOffset)
+}
+
+func TestGoroutineIDConcurrent(t *testing.T) {
+	const count = 1000
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	ids := make(map[uint64]bool, count)
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id := GetGoroutineID()
+			runtime.Gosched()
+			// the id should not change after reschedule, and same as the parsed one
+			if id != GetGoroutineID() || id != parseGoroutineID() {
+				t.Errorf("wrong goroutine id %d, parsed=%d", id, parseGoroutineID())
+			}
+			mu.Lock()
+			ids[id] = true
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if len(ids) != count {
+		t.Errorf("goroutine ids should be unique, count=%d", len(ids))
+	}
+}
+
+func TestGoroutineIDAfterStackGrow(t *testing.T) {
+	var grow func(n int) uint64
+	grow = func(n int) uint64 {
+		var buf [256]byte
+		if n == 0 {
+			return GetGoroutineID() + uint64(buf[0])
+		}
+		return grow(n - 1)
+	}
+	if id := grow(200); id != parseGoroutineID() {
+		t.Errorf("wrong goroutine id after stack grow %d", id)
+	}
+}
+
+func BenchmarkGetGoroutineID(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = GetGoroutineID()
+	}
+}
+
+func BenchmarkParseGoroutineID(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = parseGoroutineID()
+	}
+}
+
+func TestGoroutineIDFallback(t *testing.T) {
+	fastID := GetGoroutineID()
+	oldOffset := goidOffset
+	goidOffset = 0
+	defer func() { goidOffset = oldOffset }()
+
+	if id := GetGoroutineID(); id != fastID {
+		t.Errorf("fallback id %d != fast id %d", id, fastID)
+	}
+}
@@ -0,0 +1,224 @@
+package flog
+
+import (
+	"expvar"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// Hook is called with the full record for the levels it subscribes to, before the record is sampled(see
+// SetSampling) and written to the sink, so it sees the records dropped by sampling but not the summary lines.
+// it's only fired by the builtin loggers(not the logger created by SetLoggerFactory), and must be safe for
+// concurrent use. the record should not be modified, and should be copied if kept after Fire returns.
+type Hook interface {
+	// Levels returns the levels to fire, nil means all levels
+	Levels() []Level
+	Fire(r *Record) error
+}
+
+// HookHandle is returned by AddHook to unregister the hook by RemoveHook, the zero value removes nothing
+type HookHandle struct {
+	id uint64
+}
+
+// hookEntry is one registration of AddHook, the hook itself is never compared since it may be not comparable
+type hookEntry struct {
+	id   uint64
+	hook Hook
+}
+
+// hookTable is the hooks of every level, index is Level, copy on write
+type hookTable [TraceLevel + 1][]hookEntry
+
+var (
+	_hooksMu    sync.Mutex
+	_hooks      atomic.Value // *hookTable, nil when there is no hook
+	_nextHookID uint64       // guarded by _hooksMu
+)
+
+// AddHook registers the hook, and returns the handle to unregister it, example:
+//
+//	counter := flog.NewCounterHook("flog", flog.WarnLevel, flog.ErrorLevel)
+//	handle := flog.AddHook(counter)
+//	defer flog.RemoveHook(handle)
+func AddHook(hook Hook) HookHandle {
+	_hooksMu.Lock()
+	defer _hooksMu.Unlock()
+
+	table := &hookTable{}
+	if old := loadHooks(); old != nil {
+		*table = *old
+	}
+	_nextHookID++
+	entry := hookEntry{id: _nextHookID, hook: hook}
+	levels := hook.Levels()
+	if levels == nil {
+		levels = AllLevels
+	}
+	for _, level := range levels {
+		if level.IsValid() {
+			// full slice expression, so append always copies
+			table[level] = append(table[level][:len(table[level]):len(table[level])], entry)
+		}
+	}
+	_hooks.Store(table)
+	return HookHandle{id: entry.id}
+}
+
+// RemoveHook unregisters the hook registered by AddHook
+func RemoveHook(handle HookHandle) {
+	_hooksMu.Lock()
+	defer _hooksMu.Unlock()
+
+	old := loadHooks()
+	if old == nil {
+		return
+	}
+	table, empty := &hookTable{}, true
+	for level, hooks := range old {
+		for _, entry := range hooks {
+			if entry.id != handle.id {
+				table[level] = append(table[level], entry)
+				empty = false
+			}
+		}
+	}
+	if empty {
+		table = nil
+	}
+	_hooks.Store(table)
+}
+
+// ResetHooks unregisters all the hooks
+func ResetHooks() {
+	_hooksMu.Lock()
+	defer _hooksMu.Unlock()
+	_hooks.Store((*hookTable)(nil))
+}
+
+func loadHooks() *hookTable {
+	table, _ := _hooks.Load().(*hookTable)
+	return table
+}
+
+// hasHooks returns whether some hooks subscribe to the level
+func hasHooks(level Level) bool {
+	table := loadHooks()
+	return table != nil && level.IsValid() && len(table[level]) > 0
+}
+
+// fireHooks calls the hooks of the record's level, the error is output to stderr and doesn't stop the log
+func fireHooks(r *Record) {
+	table := loadHooks()
+	if table == nil || !r.Level.IsValid() {
+		return
+	}
+	for _, entry := range table[r.Level] {
+		if err := entry.hook.Fire(r); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "flog: fire hook %T fail, err=%v\n", entry.hook, err)
+		}
+	}
+}
+
+// CounterHook counts the records per level and per callsite, and exports them by expvar(/debug/vars):
+//
+//	"flog": {"levels": {"WARN": 12}, "callsites": {"WARN debugutil/verify.go:42": 10}}
+type CounterHook struct {
+	levels     []Level
+	byLevel    *expvar.Map
+	byCallsite *expvar.Map
+}
+
+// NewCounterHook returns the CounterHook which publishes the counters as expvar name,
+// the counters are shared if the name is published before. empty levels means all levels.
+func NewCounterHook(name string, levels ...Level) *CounterHook {
+	root, ok := expvar.Get(name).(*expvar.Map)
+	if !ok {
+		root = expvar.NewMap(name)
+	}
+	h := &CounterHook{levels: levels}
+	h.byLevel = expvarSubMap(root, "levels")
+	h.byCallsite = expvarSubMap(root, "callsites")
+	return h
+}
+
+func expvarSubMap(root *expvar.Map, key string) *expvar.Map {
+	if m, ok := root.Get(key).(*expvar.Map); ok {
+		return m
+	}
+	m := new(expvar.Map).Init()
+	root.Set(key, m)
+	return m
+}
+
+func (h *CounterHook) Levels() []Level {
+	return h.levels
+}
+
+func (h *CounterHook) Fire(r *Record) error {
+	h.byLevel.Add(r.Level.String(), 1)
+	h.byCallsite.Add(callsiteKey(r.Level, r.File, r.Line), 1)
+	return nil
+}
+
+// Count returns the count of the level
+func (h *CounterHook) Count(level Level) int64 {
+	return expvarInt(h.byLevel, level.String())
+}
+
+// CallsiteCount returns the count of the level at the callsite, file can be full path or short path
+func (h *CounterHook) CallsiteCount(level Level, file string, line int) int64 {
+	return expvarInt(h.byCallsite, callsiteKey(level, file, line))
+}
+
+func expvarInt(m *expvar.Map, key string) int64 {
+	if v, ok := m.Get(key).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+// callsiteKey example: "WARN debugutil/verify.go:42"
+func callsiteKey(level Level, file string, line int) string {
+	return level.String() + " " + shortFilePath(file) + ":" + strconv.Itoa(line)
+}
+
+// ChannelHook sends the copies of the records to the channel without blocking, the record is dropped when the
+// channel is full
+type ChannelHook struct {
+	ch      chan<- *Record
+	levels  []Level
+	dropped uint64
+}
+
+// NewChannelHook returns the ChannelHook sends to ch, empty levels means all levels, example:
+//
+//	alerts := make(chan *flog.Record, 100)
+//	flog.AddHook(flog.NewChannelHook(alerts, flog.ErrorLevel, flog.FatalLevel))
+func NewChannelHook(ch chan<- *Record, levels ...Level) *ChannelHook {
+	return &ChannelHook{ch: ch, levels: levels}
+}
+
+func (h *ChannelHook) Levels() []Level {
+	return h.levels
+}
+
+func (h *ChannelHook) Fire(r *Record) error {
+	// the record is kept by the receiver after Fire returns
+	record := *r
+	record.Fields = append([]Field(nil), r.Fields...)
+	select {
+	case h.ch <- &record:
+	default:
+		atomic.AddUint64(&h.dropped, 1)
+	}
+	return nil
+}
+
+// Dropped returns the count of records dropped because the channel is full
+func (h *ChannelHook) Dropped() uint64 {
+	return atomic.LoadUint64(&h.dropped)
+}
@@ -0,0 +1,145 @@
+package flog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"strings"
+	"testing"
+	"time"
+)
+
+type failHook struct {
+	count int
+}
+
+func (h *failHook) Levels() []Level {
+	return []Level{ErrorLevel}
+}
+
+func (h *failHook) Fire(r *Record) error {
+	h.count++
+	return errors.New("fail")
+}
+
+func TestHooks(t *testing.T) {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	defer SetOutput(stdLogWriter{})
+	defer ResetHooks()
+
+	counter := NewCounterHook("flog_test", WarnLevel, ErrorLevel)
+	alerts := make(chan *Record, 1)
+	channelHook := NewChannelHook(alerts, ErrorLevel)
+	fail := &failHook{}
+	counterHandle := AddHook(counter)
+	AddHook(channelHook)
+	failHandle := AddHook(fail)
+
+	for i := 0; i < 3; i++ {
+		WarnExWithPosf("/src/debugutil/verify.go", 42, "checkAndHandleError", "verify fail")
+	}
+	Infof("info")
+	With("upload", "u1").Errorf("first error")
+	Errorf("second error")
+
+	if counter.Count(WarnLevel) != 3 || counter.Count(ErrorLevel) != 2 || counter.Count(InfoLevel) != 0 {
+		t.Errorf("wrong count, warn=%d, error=%d", counter.Count(WarnLevel), counter.Count(ErrorLevel))
+	}
+	if counter.CallsiteCount(WarnLevel, "debugutil/verify.go", 42) != 3 {
+		t.Errorf("wrong callsite count %d", counter.CallsiteCount(WarnLevel, "debugutil/verify.go", 42))
+	}
+	var exported map[string]map[string]int64
+	if err := json.Unmarshal([]byte(expvar.Get("flog_test").String()), &exported); err != nil ||
+		exported["callsites"]["WARN debugutil/verify.go:42"] != 3 || exported["levels"]["ERROR"] != 2 {
+		t.Errorf("wrong expvar %s, err=%v", expvar.Get("flog_test").String(), err)
+	}
+	if NewCounterHook("flog_test").Count(WarnLevel) != 3 {
+		t.Errorf("the counters of same name should be shared")
+	}
+
+	select {
+	case r := <-alerts:
+		if r.Message != "first error" || len(r.Fields) != 1 || r.Fields[0].Key != "upload" || r.GoroutineID == 0 {
+			t.Errorf("wrong record %+v", r)
+		}
+	default:
+		t.Errorf("should receive the record")
+	}
+	if channelHook.Dropped() != 1 || fail.count != 2 {
+		t.Errorf("wrong dropped %d, fail count %d", channelHook.Dropped(), fail.count)
+	}
+
+	RemoveHook(failHandle)
+	RemoveHook(counterHandle)
+	Errorf("third error")
+	if fail.count != 2 || counter.Count(ErrorLevel) != 2 {
+		t.Errorf("the hooks should be removed")
+	}
+	if len(alerts) != 1 || (<-alerts).Message != "third error" {
+		t.Errorf("the channel hook should be kept")
+	}
+}
+
+// sliceHook is not comparable
+type sliceHook struct {
+	fired *int
+	_     []int
+}
+
+func (h sliceHook) Levels() []Level {
+	return nil
+}
+
+func (h sliceHook) Fire(r *Record) error {
+	*h.fired++
+	return nil
+}
+
+func TestRemoveHook(t *testing.T) {
+	defer ResetHooks()
+	fired := 0
+	first, second := AddHook(sliceHook{fired: &fired}), AddHook(sliceHook{fired: &fired})
+	RemoveHook(first)
+	RemoveHook(HookHandle{})
+	fireHooks(&Record{Level: InfoLevel})
+	RemoveHook(second)
+	fireHooks(&Record{Level: InfoLevel})
+	if fired != 1 || loadHooks() != nil {
+		t.Errorf("wrong fired count %d", fired)
+	}
+}
+
+func TestChannelHookCopy(t *testing.T) {
+	alerts := make(chan *Record, 1)
+	r := &Record{Level: ErrorLevel, Message: "error", Fields: []Field{{Key: "k", Value: "v"}}}
+	_ = NewChannelHook(alerts).Fire(r)
+	r.Message, r.Fields[0].Value = "reused", "changed"
+	if got := <-alerts; got == r || got.Message != "error" || got.Fields[0].Value != "v" {
+		t.Errorf("the hook should send the copy, got %+v", got)
+	}
+}
+
+func TestHooksWithSampling(t *testing.T) {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	defer SetOutput(stdLogWriter{})
+	SetSampling(&SamplingConfig{Window: time.Hour, First: 2})
+	defer SetSampling(nil)
+	defer ResetHooks()
+
+	// the hooks count every warning, the sampled ones and the summary line are only for the output
+	counter := NewCounterHook("flog_sampling_test", WarnLevel)
+	AddHook(counter)
+	for i := 0; i < 1000; i++ {
+		WarnExWithPosf("/src/debugutil/verify.go", 42, "checkAndHandleError", "verify fail")
+	}
+	_ = Flush()
+	if counter.Count(WarnLevel) != 1000 || counter.CallsiteCount(WarnLevel, "debugutil/verify.go", 42) != 1000 {
+		t.Errorf("wrong count %d", counter.Count(WarnLevel))
+	}
+	if output := buf.String(); strings.Count(output, "verify fail") != 2 || !strings.Contains(output, "suppressed 998 similar messages") {
+		t.Errorf("wrong output: %s", output)
+	}
+}
@@ -0,0 +1,72 @@
+// Package auditchain is the hash chain format shared by flog.AuditSink and auditverify
+package auditchain
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// the keys of the hash chain in the audit record
+const (
+	SeqKey  = "seq"
+	PrevKey = "prev"
+	HashKey = "hash"
+)
+
+// HashPrefix is before the hash, the record is signed until here
+const HashPrefix = `,"` + HashKey + `":"`
+
+// GenesisHash is the "prev" of the first audit record
+var GenesisHash = strings.Repeat("0", sha256.Size*2)
+
+// Entry is the hash chain part of one audit record
+type Entry struct {
+	Seq  uint64
+	Prev string
+	Hash string
+	// Body is the signed content, the line before `,"hash":"`
+	Body []byte
+}
+
+// Hash returns the hex SHA-256 of the body, or HMAC-SHA256 if the key is not empty
+func Hash(key []byte, body []byte) string {
+	if len(key) == 0 {
+		sum := sha256.Sum256(body)
+		return hex.EncodeToString(sum[:])
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ParseLine parses the hash chain of the audit record, the line ending is optional
+func ParseLine(line []byte) (*Entry, error) {
+	line = bytes.TrimRight(line, "\r\n")
+	index := bytes.LastIndex(line, []byte(HashPrefix))
+	if index < 0 || !bytes.HasSuffix(line, []byte(`"}`)) {
+		return nil, errors.New("no hash")
+	}
+	entry := &Entry{Body: line[:index], Hash: string(line[index+len(HashPrefix) : len(line)-2])}
+	if len(entry.Hash) != sha256.Size*2 {
+		return nil, fmt.Errorf("invalid hash %q", entry.Hash)
+	}
+
+	var chain struct {
+		Seq  *uint64 `json:"seq"`
+		Prev *string `json:"prev"`
+	}
+	if err := json.Unmarshal(append(append([]byte(nil), entry.Body...), '}'), &chain); err != nil {
+		return nil, fmt.Errorf("invalid json: %w", err)
+	}
+	if chain.Seq == nil || chain.Prev == nil {
+		return nil, errors.New("no seq or prev")
+	}
+	entry.Seq, entry.Prev = *chain.Seq, *chain.Prev
+	return entry, nil
+}
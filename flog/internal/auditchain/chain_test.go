@@ -0,0 +1,23 @@
+package auditchain
+
+import (
+	"testing"
+)
+
+func TestParseLine(t *testing.T) {
+	hash := Hash(nil, []byte(`{"msg":"x","seq":1,"prev":"`+GenesisHash+`"`))
+	entry, err := ParseLine([]byte(`{"msg":"x","seq":1,"prev":"` + GenesisHash + `","hash":"` + hash + "\"}\r\n"))
+	if err != nil || entry.Seq != 1 || entry.Hash != hash {
+		t.Errorf("parse fail: %+v, err=%v", entry, err)
+	}
+	for _, line := range []string{
+		`{"msg":"x"}`,
+		`{"msg":"x","seq":1,"prev":"0","hash":"abc"}`,
+		`{"msg":"x","hash":"` + hash + `"}`,
+		`{"msg":x,"seq":1,"prev":"0","hash":"` + hash + `"}`,
+	} {
+		if _, err = ParseLine([]byte(line)); err == nil {
+			t.Errorf("parse %s should fail", line)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package flog
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvLayout is the environment variable of the layout of the default logger, read when init, see ParseLayout
+const EnvLayout = "FLOG_LAYOUT"
+
+// the named time formats of the layout
+var layoutTimeFormats = map[string]string{
+	"rfc3339":      time.RFC3339,
+	"rfc3339milli": "2006-01-02T15:04:05.000Z07:00",
+	"rfc3339micro": "2006-01-02T15:04:05.000000Z07:00",
+}
+
+// defaultLayoutTime is the time format of {time} and {utc} without format, same as go std log
+const defaultLayoutTime = "2006/01/02 15:04:05"
+
+type layoutKind uint8
+
+const (
+	layoutText layoutKind = iota
+	layoutTime
+	layoutUTCTime
+	layoutLevel
+	layoutLegacyLevel
+	layoutFile
+	layoutShortFile
+	layoutFullFile
+	layoutLine
+	layoutFunc
+	layoutPid
+	layoutGid
+	layoutFields
+	layoutMessage
+)
+
+// layoutSimpleKinds are the placeholders without argument
+var layoutSimpleKinds = map[string]layoutKind{
+	"line": layoutLine, "func": layoutFunc, "pid": layoutPid, "gid": layoutGid, "msg": layoutMessage,
+}
+
+// layoutPart is the literal text or one placeholder, arg is the time format or the text of empty fields
+type layoutPart struct {
+	kind layoutKind
+	arg  string
+}
+
+// Layout is the parsed layout template of TextEncoder, it's executed by appending the parts one by one
+type Layout struct {
+	spec  string
+	parts []layoutPart
+}
+
+// ParseLayout parses the layout template, the placeholders are:
+//   - {time} or {time:FORMAT}: the local time, FORMAT is the layout of time.Format or "rfc3339", "rfc3339milli",
+//     "rfc3339micro", default is "2006/01/02 15:04:05", example: {time:2006-01-02T15:04:05.000000Z07:00}
+//   - {utc} or {utc:FORMAT}: same as {time} but in UTC
+//   - {level} or {level:legacy}: example: "WARN" and "DEBUG", or "WARN" and "Debug" of the old default logger
+//   - {file}, {file:short}, {file:full}: "verify.go", "debugutil/verify.go" or the full path
+//   - {line}, {func}, {pid}, {gid}, {msg}
+//   - {fields} or {fields:TEXT}: "k1=v1 k2=v2", TEXT is output when there is no field, example: {fields:none}
+//
+// "{{" and "}}" are the literal braces, example:
+//
+//	{time:rfc3339milli} {level} {file}:{line} {func} g{gid} {fields} {msg}
+func ParseLayout(spec string) (*Layout, error) {
+	layout := &Layout{spec: spec}
+	var text strings.Builder
+	flushText := func() {
+		if text.Len() > 0 {
+			layout.parts = append(layout.parts, layoutPart{kind: layoutText, arg: text.String()})
+			text.Reset()
+		}
+	}
+	for i := 0; i < len(spec); i++ {
+		c := spec[i]
+		switch {
+		case c == '{' && strings.HasPrefix(spec[i:], "{{"), c == '}' && strings.HasPrefix(spec[i:], "}}"):
+			text.WriteByte(c)
+			i++
+		case c == '}':
+			return nil, fmt.Errorf("flog: unexpected '}' at %d of layout %q", i, spec)
+		case c == '{':
+			end := strings.IndexByte(spec[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("flog: unclosed '{' at %d of layout %q", i, spec)
+			}
+			part, err := parseLayoutPart(spec[i+1 : i+end])
+			if err != nil {
+				return nil, err
+			}
+			flushText()
+			layout.parts = append(layout.parts, part)
+			i += end
+		default:
+			text.WriteByte(c)
+		}
+	}
+	flushText()
+	return layout, nil
+}
+
+// MustParseLayout is like ParseLayout but panics if the spec is invalid
+func MustParseLayout(spec string) *Layout {
+	layout, err := ParseLayout(spec)
+	if err != nil {
+		panic(err)
+	}
+	return layout
+}
+
+// parseLayoutPart parses "name" or "name:arg" of the placeholder
+func parseLayoutPart(placeholder string) (layoutPart, error) {
+	name, arg, hasArg := placeholder, "", false
+	if index := strings.IndexByte(placeholder, ':'); index >= 0 {
+		name, arg, hasArg = placeholder[:index], placeholder[index+1:], true
+	}
+	switch name {
+	case "time", "utc":
+		kind := layoutTime
+		if name == "utc" {
+			kind = layoutUTCTime
+		}
+		if named, ok := layoutTimeFormats[arg]; ok {
+			arg = named
+		} else if arg == "" {
+			arg = defaultLayoutTime
+		}
+		return layoutPart{kind: kind, arg: arg}, nil
+	case "file":
+		switch arg {
+		case "":
+			return layoutPart{kind: layoutFile}, nil
+		case "short":
+			return layoutPart{kind: layoutShortFile}, nil
+		case "full":
+			return layoutPart{kind: layoutFullFile}, nil
+		}
+		return layoutPart{}, fmt.Errorf("flog: invalid layout {%s}, should be {file}, {file:short} or {file:full}", placeholder)
+	case "fields":
+		return layoutPart{kind: layoutFields, arg: arg}, nil
+	case "level":
+		switch arg {
+		case "":
+			return layoutPart{kind: layoutLevel}, nil
+		case "legacy":
+			return layoutPart{kind: layoutLegacyLevel}, nil
+		}
+		return layoutPart{}, fmt.Errorf("flog: invalid layout {%s}, should be {level} or {level:legacy}", placeholder)
+	}
+	kind, ok := layoutSimpleKinds[name]
+	if !ok {
+		return layoutPart{}, fmt.Errorf("flog: unknown layout placeholder {%s}", placeholder)
+	}
+	if hasArg {
+		return layoutPart{}, fmt.Errorf("flog: layout {%s} has no argument", name)
+	}
+	return layoutPart{kind: kind}, nil
+}
+
+// String returns the spec of the layout
+func (l *Layout) String() string {
+	return l.spec
+}
+
+// Append executes the layout, without the line ending
+func (l *Layout) Append(buf *bytes.Buffer, r *Record) {
+	var num [64]byte
+	for i := range l.parts {
+		part := &l.parts[i]
+		switch part.kind {
+		case layoutText:
+			buf.WriteString(part.arg)
+		case layoutTime:
+			buf.Write(r.Time.AppendFormat(num[:0], part.arg))
+		case layoutUTCTime:
+			buf.Write(r.Time.UTC().AppendFormat(num[:0], part.arg))
+		case layoutLevel:
+			buf.WriteString(r.Level.String())
+		case layoutLegacyLevel:
+			buf.WriteString(legacyLevelName(r.Level))
+		case layoutFile:
+			buf.WriteString(path.Base(r.File))
+		case layoutShortFile:
+			buf.WriteString(shortFilePath(r.File))
+		case layoutFullFile:
+			buf.WriteString(r.File)
+		case layoutLine:
+			buf.Write(strconv.AppendInt(num[:0], int64(r.Line), 10))
+		case layoutFunc:
+			buf.WriteString(r.Function)
+		case layoutPid:
+			buf.Write(strconv.AppendInt(num[:0], int64(r.Pid), 10))
+		case layoutGid:
+			buf.Write(strconv.AppendUint(num[:0], r.GoroutineID, 10))
+		case layoutFields:
+			if len(r.Fields) > 0 {
+				buf.WriteString(formatFieldsText(r.Fields))
+			} else {
+				buf.WriteString(part.arg)
+			}
+		case layoutMessage:
+			buf.WriteString(r.Message)
+		}
+	}
+}
+
+// SetLayout sets the TextEncoder with the layout to the default WriterSink, see ParseLayout
+func SetLayout(spec string) error {
+	layout, err := ParseLayout(spec)
+	if err != nil {
+		return err
+	}
+	SetEncoder(&TextEncoder{Layout: layout})
+	return nil
+}
+
+func init() {
+	if spec := os.Getenv(EnvLayout); spec != "" {
+		if err := SetLayout(spec); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "flog: invalid %s=%q, err=%v\n", EnvLayout, spec, err)
+		}
+	}
+}
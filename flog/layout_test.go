@@ -0,0 +1,101 @@
+package flog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newLayoutTestRecord(fields ...Field) *Record {
+	r := newTestRecord()
+	r.Time = time.Date(2024, 5, 6, 7, 8, 9, 123456789, time.FixedZone("CST", 8*3600))
+	r.Function, r.Message, r.Fields = "Verify", "hello", fields
+	return r
+}
+
+func TestLayout(t *testing.T) {
+	cases := []struct {
+		spec     string
+		fields   []Field
+		expected string
+	}{
+		{"{time:rfc3339micro} {level} {file}:{line} {func} g{gid} {fields} {msg}", []Field{{Key: "k", Value: "v"}},
+			"2024-05-06T07:08:09.123456+08:00 WARN verify.go:42 Verify g7 k=v hello"},
+		{"{utc:2006-01-02 15:04:05.000000} {file:short} {file:full}", nil,
+			"2024-05-05 23:08:09.123456 debugutil/verify.go /src/debugutil/verify.go"},
+		{"{time} [{fields:none}][{fields}] {pid}", nil, "2024/05/06 07:08:09 [none][] 100"},
+		{"{{literal}} {msg}}}", nil, "{literal} hello}"},
+		{"", nil, ""},
+	}
+	for _, c := range cases {
+		layout, err := ParseLayout(c.spec)
+		if err != nil {
+			t.Fatalf("parse %q: %v", c.spec, err)
+		}
+		var buf bytes.Buffer
+		layout.Append(&buf, newLayoutTestRecord(c.fields...))
+		if buf.String() != c.expected || layout.String() != c.spec {
+			t.Errorf("layout %q: got %q, want %q", c.spec, buf.String(), c.expected)
+		}
+	}
+
+	for _, spec := range []string{"{msg", "msg}", "{unknown}", "{level:x}", "{file:long}", "{}"} {
+		if _, err := ParseLayout(spec); err == nil {
+			t.Errorf("parse %q should fail", spec)
+		}
+	}
+}
+
+func TestTextEncoderLayout(t *testing.T) {
+	var buf bytes.Buffer
+	encoder := &TextEncoder{TimeFormat: "2006/01/02", Layout: MustParseLayout("{level} {msg}")}
+	_ = encoder.Encode(&buf, newLayoutTestRecord())
+	if buf.String() != "WARN hello\n" {
+		t.Errorf("wrong output %q", buf.String())
+	}
+
+	// the default layout is same as before
+	buf.Reset()
+	_ = NewTextEncoder().Encode(&buf, newLayoutTestRecord(Field{Key: "k", Value: 1}))
+	if buf.String() != "2024/05/06 07:08:09 [ verify.go:42 ][100][7][WARN][k=1] hello\n" {
+		t.Errorf("wrong default output %q", buf.String())
+	}
+
+	// the default layout keeps the level names of the old default logger
+	buf.Reset()
+	r := newLayoutTestRecord()
+	r.Level = DebugLevel
+	_ = NewTextEncoder().Encode(&buf, r)
+	if buf.String() != "2024/05/06 07:08:09 [ verify.go:42 ][100][7][Debug][none] hello\n" {
+		t.Errorf("wrong default debug output %q", buf.String())
+	}
+
+	buf.Reset()
+	SetOutput(&buf)
+	defer func() {
+		SetEncoder(NewTextEncoder())
+		SetOutput(stdLogWriter{})
+	}()
+	if err := SetLayout("{level}|{file}|{msg}"); err != nil {
+		t.Fatal(err)
+	}
+	Warnf("layout %d", 1)
+	if !strings.HasPrefix(buf.String(), "WARN|layout_test.go|layout 1\n") {
+		t.Errorf("wrong output %q", buf.String())
+	}
+	if SetLayout("{bad}") == nil {
+		t.Errorf("invalid layout should fail")
+	}
+}
+
+func BenchmarkLayout(b *testing.B) {
+	layout := MustParseLayout("{time:rfc3339micro} {level} {file:short}:{line} {func} g{gid} {fields} {msg}")
+	r := newLayoutTestRecord(Field{Key: "k", Value: "v"})
+	var buf bytes.Buffer
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		layout.Append(&buf, r)
+	}
+}
@@ -0,0 +1,59 @@
+package flog
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// lazyValue is returned by Lazy, evaluated when the record is output
+type lazyValue func() any
+
+// Lazy wraps the expensive argument(or field value), which is only evaluated when the record is output, example:
+//
+//	flog.Debugf("reader type=%s", flog.Lazy(func() any { return reflect.TypeOf(reader).String() }))
+func Lazy(fn func() any) any {
+	return lazyValue(fn)
+}
+
+// Enabled returns whether the level is enabled by the current logger(and vmodule) at the callsite,
+// so the caller can skip preparing the expensive arguments:
+//
+//	if flog.Enabled(flog.DebugLevel) {
+//		flog.Debugf("dump: %s", dump(req))
+//	}
+func Enabled(level Level) bool {
+	return isEnabledAt(curLogger().GetLevel(), level, 2)
+}
+
+// EnabledFor is same as Enabled for the logger(example: the named logger returned by GetLogger), the call through
+// the interface allocates the arguments even if the level is disabled, so guard the hot path by it:
+//
+//	if flog.EnabledFor(logger, flog.DebugLevel) {
+//		logger.Debugf("reader type=%s", reflect.TypeOf(reader))
+//	}
+func EnabledFor(logger ILoggerEx, level Level) bool {
+	return isEnabledAt(logger.GetLevel(), level, 2)
+}
+
+// IsDebugEnabled is same as Enabled(DebugLevel)
+func IsDebugEnabled() bool {
+	return isEnabledAt(curLogger().GetLevel(), DebugLevel, 2)
+}
+
+// IsTraceEnabled is same as Enabled(TraceLevel)
+func IsTraceEnabled() bool {
+	return isEnabledAt(curLogger().GetLevel(), TraceLevel, 2)
+}
+
+// formatMessage formats the message by the pooled buffer, evaluates the Lazy arguments and redacts the secrets
+func formatMessage(format string, args []any) string {
+	if len(args) == 0 && strings.IndexByte(format, '%') < 0 {
+		return redactMessage(format)
+	}
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer bufferPool.Put(buf)
+	_, _ = fmt.Fprintf(buf, format, resolveArgs(args)...)
+	return redactMessage(buf.String())
+}
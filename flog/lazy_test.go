@@ -0,0 +1,114 @@
+package flog
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLazy(t *testing.T) {
+	tl := useRecordLogger(t)
+	tl.SetLevel(InfoLevel)
+
+	evaluated := 0
+	lazy := Lazy(func() any {
+		evaluated++
+		return "expensive"
+	})
+	Debugf("disabled %s", lazy)
+	if evaluated != 0 {
+		t.Errorf("lazy should not be evaluated for disabled level")
+	}
+	Infof("enabled %s", lazy)
+	With("k", lazy).Warnf("field")
+	records := tl.Records()
+	if evaluated != 2 || len(records) != 2 || records[0].Message != "enabled expensive" || records[1].Fields[0].Value != "expensive" {
+		t.Errorf("wrong records %+v, evaluated=%d", records, evaluated)
+	}
+
+	if IsDebugEnabled() || IsTraceEnabled() || !Enabled(InfoLevel) || !Enabled(FatalLevel) {
+		t.Errorf("wrong enabled result")
+	}
+	defer func() { _ = SetVModule("") }()
+	_ = SetVModule("lazy_test=debug")
+	if !IsDebugEnabled() {
+		t.Errorf("vmodule should be applied to the callsite")
+	}
+}
+
+func TestFormatMessage(t *testing.T) {
+	for _, item := range []struct {
+		format   string
+		args     []any
+		expected string
+	}{
+		{"plain", nil, "plain"},
+		{"100%%", nil, "100%"},
+		{"%d-%s", []any{1, Lazy(func() any { return "a" })}, "1-a"},
+	} {
+		if msg := formatMessage(item.format, item.args); msg != item.expected {
+			t.Errorf("wrong message %q, expected %q", msg, item.expected)
+		}
+	}
+}
+
+func benchmarkDisabled(b *testing.B, fn func()) {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	defer SetOutput(stdLogWriter{})
+	oldLevel := GetLevel()
+	defer SetLevel(oldLevel)
+	SetLevel(InfoLevel)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fn()
+	}
+	b.StopTimer()
+	if buf.Len() != 0 {
+		b.Fatalf("should not output: %s", buf.String())
+	}
+}
+
+func BenchmarkDisabledDebugf(b *testing.B) {
+	record := &Record{}
+	benchmarkDisabled(b, func() {
+		Debugf("record=%p, line=%d", record, 10)
+	})
+}
+
+func BenchmarkDisabledIsDebugEnabled(b *testing.B) {
+	name := "abc"
+	benchmarkDisabled(b, func() {
+		if IsDebugEnabled() {
+			Debugf("name=%s", name)
+		}
+	})
+}
+
+func BenchmarkDisabledLazy(b *testing.B) {
+	benchmarkDisabled(b, func() {
+		Debugf("type=%s", Lazy(func() any { return strings.Repeat("a", 100) }))
+	})
+}
+
+func BenchmarkEnabledInfof(b *testing.B) {
+	SetOutput(io.Discard)
+	defer SetOutput(stdLogWriter{})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Infof("line=%d, name=%s", i, "abc")
+	}
+}
+
+func BenchmarkDisabledNamedLogger(b *testing.B) {
+	logger := GetLogger("bench")
+	name := "abc"
+	benchmarkDisabled(b, func() {
+		if EnabledFor(logger, DebugLevel) {
+			logger.Debugf("name=%s", name)
+		}
+	})
+}
@@ -0,0 +1,110 @@
+package flog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Level is the log level, same as logrus's Level: Trace(6)>Debug(5)>Info(4)>Warn(3)>Error(2)>Fatal(1)>Panic(0),
+// a logger with level X will output all the logs whose level <= X.
+type Level uint32
+
+const (
+	// PanicLevel logs and then calls panic with the message
+	PanicLevel Level = iota
+	// FatalLevel logs and then calls `os.Exit(1)`
+	FatalLevel
+	ErrorLevel
+	WarnLevel
+	InfoLevel
+	DebugLevel
+	TraceLevel
+)
+
+var levelNames = [...]string{
+	PanicLevel: "PANIC",
+	FatalLevel: "FATAL",
+	ErrorLevel: "ERROR",
+	WarnLevel:  "WARN",
+	InfoLevel:  "INFO",
+	DebugLevel: "DEBUG",
+	TraceLevel: "TRACE",
+}
+
+// legacyLevelNames are the level names of the old default logger(before Level was added)
+var legacyLevelNames = [...]string{
+	PanicLevel: "PANIC",
+	FatalLevel: "FATAL",
+	ErrorLevel: "ERROR",
+	WarnLevel:  "WARN",
+	InfoLevel:  "Info",
+	DebugLevel: "Debug",
+	TraceLevel: "Trace",
+}
+
+// AllLevels contains all the valid levels, from Panic to Trace
+var AllLevels = []Level{PanicLevel, FatalLevel, ErrorLevel, WarnLevel, InfoLevel, DebugLevel, TraceLevel}
+
+func (level Level) String() string {
+	if level <= TraceLevel {
+		return levelNames[level]
+	}
+	return "Level(" + strconv.Itoa(int(level)) + ")"
+}
+
+// legacyLevelName returns the level name of the old default logger, same as String for the invalid level
+func legacyLevelName(level Level) string {
+	if level <= TraceLevel {
+		return legacyLevelNames[level]
+	}
+	return level.String()
+}
+
+// IsValid return true if the level is one of the AllLevels
+func (level Level) IsValid() bool {
+	return level <= TraceLevel
+}
+
+// ParseLevel takes a string level(case-insensitive, example: "debug", "WARN", "warning" or "5") and returns the Level
+func ParseLevel(text string) (Level, error) {
+	text = strings.TrimSpace(text)
+	switch strings.ToLower(text) {
+	case "panic":
+		return PanicLevel, nil
+	case "fatal":
+		return FatalLevel, nil
+	case "error", "err":
+		return ErrorLevel, nil
+	case "warn", "warning":
+		return WarnLevel, nil
+	case "info":
+		return InfoLevel, nil
+	case "debug":
+		return DebugLevel, nil
+	case "trace":
+		return TraceLevel, nil
+	}
+	if n, err := strconv.ParseUint(text, 10, 32); err == nil && Level(n).IsValid() {
+		return Level(n), nil
+	}
+	return PanicLevel, fmt.Errorf("not a valid flog Level: %q", text)
+}
+
+// MarshalText implements encoding.TextMarshaler, so Level can be used in json/yaml config directly
+func (level Level) MarshalText() ([]byte, error) {
+	if !level.IsValid() {
+		return nil, fmt.Errorf("not a valid flog Level %d", uint32(level))
+	}
+	return []byte(level.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (level *Level) UnmarshalText(text []byte) error {
+	l, err := ParseLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*level = l
+	return nil
+}
@@ -0,0 +1,109 @@
+package flog
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// LevelState is the JSON body of LevelHandler, example:
+//
+//	{"level":"DEBUG","vmodule":"repeatable_reader=TRACE,ioext=INFO"}
+type LevelState struct {
+	// Level is the level of the current logger, can be name or number string when PUT, example: "debug" or "5"
+	Level *Level `json:"level,omitempty"`
+	// VModule is the module level overrides(see SetVModule), empty string clears all the rules
+	VModule *string `json:"vmodule,omitempty"`
+}
+
+// _levelStateMu serializes the updates of LevelHandler and level signals
+var _levelStateMu sync.Mutex
+
+// LevelHandler returns the http.Handler to change the level at runtime, example:
+//
+//	http.Handle("/debug/flog/level", flog.LevelHandler())
+//
+//	curl http://localhost:8080/debug/flog/level
+//	curl -X PUT -d '{"level":"debug","vmodule":"verify=trace"}' http://localhost:8080/debug/flog/level
+//
+// GET returns the current LevelState, PUT updates the fields present in the body and returns the new state,
+// nothing is changed when any field is invalid.
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(serveLevel)
+}
+
+func serveLevel(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeLevelJSON(w, http.StatusOK, currentLevelState())
+	case http.MethodPut:
+		var state LevelState
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&state); err != nil {
+			writeLevelJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		if err := applyLevelState(&state); err != nil {
+			writeLevelJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		writeLevelJSON(w, http.StatusOK, currentLevelState())
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		writeLevelJSON(w, http.StatusMethodNotAllowed,
+			map[string]string{"error": fmt.Sprintf("method %s not allowed", r.Method)})
+	}
+}
+
+func currentLevelState() *LevelState {
+	_levelStateMu.Lock()
+	defer _levelStateMu.Unlock()
+	level, vmodule := GetLevel(), GetVModule()
+	return &LevelState{Level: &level, VModule: &vmodule}
+}
+
+// applyLevelState validates all the fields before change anything
+func applyLevelState(state *LevelState) error {
+	if state.Level != nil && !state.Level.IsValid() {
+		return fmt.Errorf("not a valid flog Level %d", uint32(*state.Level))
+	}
+	var config *vmoduleConfig
+	if state.VModule != nil {
+		var err error
+		if config, err = parseVModule(*state.VModule); err != nil {
+			return err
+		}
+	}
+
+	_levelStateMu.Lock()
+	defer _levelStateMu.Unlock()
+	if state.VModule != nil {
+		_vmodule.Store(config)
+	}
+	if state.Level != nil {
+		SetLevel(*state.Level)
+	}
+	return nil
+}
+
+func writeLevelJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// stepLevel moves the level of the current logger by delta(positive is more verbose), returns the new level
+func stepLevel(delta int) Level {
+	_levelStateMu.Lock()
+	defer _levelStateMu.Unlock()
+	level := int(GetLevel()) + delta
+	if level < int(PanicLevel) {
+		level = int(PanicLevel)
+	} else if level > int(TraceLevel) {
+		level = int(TraceLevel)
+	}
+	SetLevel(Level(level))
+	return Level(level)
+}
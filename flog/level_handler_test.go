@@ -0,0 +1,80 @@
+package flog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func doLevelRequest(t *testing.T, method string, body string) (int, string) {
+	req := httptest.NewRequest(method, "/debug/flog/level", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	LevelHandler().ServeHTTP(rec, req)
+	if rec.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("wrong content type %s", rec.Header().Get("Content-Type"))
+	}
+	return rec.Code, strings.TrimSpace(rec.Body.String())
+}
+
+func TestLevelHandler(t *testing.T) {
+	oldLevel, oldVModule := GetLevel(), GetVModule()
+	defer func() {
+		SetLevel(oldLevel)
+		_ = SetVModule(oldVModule)
+	}()
+	SetLevel(InfoLevel)
+	_ = SetVModule("")
+
+	if code, body := doLevelRequest(t, http.MethodGet, ""); code != http.StatusOK || body != `{"level":"INFO","vmodule":""}` {
+		t.Errorf("wrong GET result %d %s", code, body)
+	}
+	code, body := doLevelRequest(t, http.MethodPut, `{"level":"debug","vmodule":"verify=trace"}`)
+	if code != http.StatusOK || body != `{"level":"DEBUG","vmodule":"verify=TRACE"}` {
+		t.Errorf("wrong PUT result %d %s", code, body)
+	}
+	if GetLevel() != DebugLevel || GetVModule() != "verify=TRACE" {
+		t.Errorf("level not changed, level=%s, vmodule=%s", GetLevel(), GetVModule())
+	}
+
+	// only change the present field
+	if code, body = doLevelRequest(t, http.MethodPut, `{"vmodule":""}`); code != http.StatusOK ||
+		body != `{"level":"DEBUG","vmodule":""}` {
+		t.Errorf("wrong PUT result %d %s", code, body)
+	}
+
+	// nothing is changed when any field is invalid
+	for _, invalid := range []string{`{"level":"warn","vmodule":"verify"}`, `{"level":"bad"}`, `{"unknown":1}`, `[`} {
+		if code, body = doLevelRequest(t, http.MethodPut, invalid); code != http.StatusBadRequest ||
+			!strings.Contains(body, `"error"`) {
+			t.Errorf("wrong result of %s: %d %s", invalid, code, body)
+		}
+	}
+	if GetLevel() != DebugLevel {
+		t.Errorf("level should not be changed by invalid request, level=%s", GetLevel())
+	}
+	if code, _ = doLevelRequest(t, http.MethodDelete, ""); code != http.StatusMethodNotAllowed {
+		t.Errorf("wrong DELETE result %d", code)
+	}
+}
+
+func TestSetLoggerFactoryKeepsLevel(t *testing.T) {
+	oldLogger, oldLevel := curLogger(), GetLevel()
+	defer func() {
+		SetLogger(oldLogger)
+		SetLevel(oldLevel)
+	}()
+
+	SetLevel(WarnLevel)
+	SetLoggerFactory(func() ILogger { return &simpleLogger{} })
+	if GetLevel() != WarnLevel {
+		t.Errorf("the new logger should use the global level, level=%s", GetLevel())
+	}
+	if level := stepLevel(1); level != InfoLevel || GetLevel() != InfoLevel {
+		t.Errorf("wrong level after step %s", level)
+	}
+	SetLevel(TraceLevel)
+	if level := stepLevel(1); level != TraceLevel {
+		t.Errorf("level should not exceed trace, level=%s", level)
+	}
+}
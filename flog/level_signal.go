@@ -0,0 +1,42 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+
+package flog
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// HandleLevelSignals changes the level of the current logger when the process receives the signals:
+//   - SIGUSR1: more verbose, example: Info => Debug => Trace
+//   - SIGUSR2: less verbose, example: Debug => Info => Warn
+//
+// it's opt-in because the default action of the signals is terminate, call the returned stop to restore, example:
+//
+//	defer flog.HandleLevelSignals()()
+//	kill -USR1 <pid>
+func HandleLevelSignals() (stop func()) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR1, syscall.SIGUSR2)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case sig := <-ch:
+				delta := 1
+				if sig == syscall.SIGUSR2 {
+					delta = -1
+				}
+				level := stepLevel(delta)
+				Warnf("flog: level changed to %s by signal %s", level, sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(ch)
+		close(done)
+	}
+}
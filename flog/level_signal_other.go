@@ -0,0 +1,8 @@
+//go:build !(aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris)
+
+package flog
+
+// HandleLevelSignals does nothing on the platform without SIGUSR1/SIGUSR2
+func HandleLevelSignals() (stop func()) {
+	return func() {}
+}
@@ -0,0 +1,35 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+
+package flog
+
+import (
+	"bytes"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestHandleLevelSignals(t *testing.T) {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	defer SetOutput(stdLogWriter{})
+	oldLevel := GetLevel()
+	defer SetLevel(oldLevel)
+	SetLevel(InfoLevel)
+
+	stop := HandleLevelSignals()
+	defer stop()
+
+	waitLevel := func(expected Level) {
+		for i := 0; i < 100 && GetLevel() != expected; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if GetLevel() != expected {
+			t.Errorf("wrong level %s, expected %s", GetLevel(), expected)
+		}
+	}
+	_ = syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+	waitLevel(DebugLevel)
+	_ = syscall.Kill(syscall.Getpid(), syscall.SIGUSR2)
+	waitLevel(InfoLevel)
+}
@@ -1,10 +1,13 @@
 package flog
 
 import (
-	"log"
+	"fmt"
+	"io"
 	"os"
-	"path"
-	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // ILogger in go-library, provide WarnExWithPosf, so can used in verify,
@@ -15,58 +18,714 @@ type ILogger interface {
 	WarnExWithPosf(fileName string, lineNo int, funName string, format string, args ...any)
 }
 
+// ILoggerEx is the full leveled logger, it's optional for the logger created by LoggerFactory,
+// the package-level functions detect it, and adapt the simple ILogger if not implemented.
+type ILoggerEx interface {
+	ILogger
+
+	Tracef(format string, args ...any)
+	Warnf(format string, args ...any)
+	Errorf(format string, args ...any)
+	// Fatalf logs and then calls `os.Exit(1)`
+	Fatalf(format string, args ...any)
+	// Panicf logs and then calls panic with the message
+	Panicf(format string, args ...any)
+
+	TraceExWithPosf(fileName string, lineNo int, funName string, format string, args ...any)
+	DebugExWithPosf(fileName string, lineNo int, funName string, format string, args ...any)
+	InfoExWithPosf(fileName string, lineNo int, funName string, format string, args ...any)
+	ErrorExWithPosf(fileName string, lineNo int, funName string, format string, args ...any)
+	FatalExWithPosf(fileName string, lineNo int, funName string, format string, args ...any)
+	PanicExWithPosf(fileName string, lineNo int, funName string, format string, args ...any)
+
+	SetLevel(level Level)
+	GetLevel() Level
+
+	// With returns a child logger which carries the key/value pairs, and output them in every record
+	With(keyValues ...any) ILoggerEx
+	// WithFields returns a child logger which carries the fields
+	WithFields(fields Fields) ILoggerEx
+}
+
 // LoggerFactory is the factory method for creating logger used for the specified package.
 type LoggerFactory func() ILogger
 
+// SetLoggerFactory replaces the current logger by the logger created by factory, it's safe for concurrent use.
+// the level set by SetLevel(or LevelHandler, level signals) before is applied to the new logger.
 func SetLoggerFactory(factory LoggerFactory) {
-	_curLogger = factory()
+	logger := toLoggerEx(factory())
+	_levelMu.Lock()
+	defer _levelMu.Unlock()
+	if _levelSet {
+		logger.SetLevel(_globalLevel)
+	}
+	SetLogger(logger)
+}
+
+// SetLogger replaces the current logger, and returns the previous one, nil means the default logger.
+// it's safe for concurrent use with the logging functions.
+func SetLogger(logger ILoggerEx) ILoggerEx {
+	if logger == nil {
+		logger = _defaultLogger
+	}
+	prev := _curLogger.Swap(loggerHolder{logger}).(loggerHolder).ILoggerEx
+	atomic.AddUint64(&_curLoggerGen, 1)
+	return prev
+}
+
+// loggerHolder make sure the atomic.Value always store the same type
+type loggerHolder struct {
+	ILoggerEx
+}
+
+// curLogger returns the current logger used by the package-level functions
+func curLogger() ILoggerEx {
+	return _curLogger.Load().(loggerHolder).ILoggerEx
+}
+
+// CurrentLogger returns the current logger used by the package-level functions
+func CurrentLogger() ILoggerEx {
+	return curLogger()
+}
+
+// exitFunc is called by Fatal logs, replaced in unit test
+var exitFunc = os.Exit
+
+// _pid is cached, os.Getpid is a syscall
+var _pid = os.Getpid()
+
+// atomicLevel is shared by the logger and its child loggers(created by With)
+type atomicLevel struct {
+	v uint32
+}
+
+func newAtomicLevel(level Level) *atomicLevel {
+	return &atomicLevel{v: uint32(level)}
+}
+
+func (a *atomicLevel) Load() Level {
+	return Level(atomic.LoadUint32(&a.v))
+}
+
+func (a *atomicLevel) Store(level Level) {
+	atomic.StoreUint32(&a.v, uint32(level))
 }
 
 type defaultLogger struct {
-	// TODO: define another flog.Level?
-	// same as : logrus's Level: Trace(6)>Debug(5)>Info(4)>Warn(3)>Error(2)>Fatal(1)>Panic(0)
-	level int
+	core   *loggerCore
+	fields []Field
+
+	// sampler is set by WithSampling when ownSampler is true, otherwise use the core's
+	sampler    *sampler
+	ownSampler bool
 }
 
-func (l *defaultLogger) innerLog(level, format string, args ...any) {
-	_, fileName, lineNo, _ := runtime.Caller(3)
-	log.Printf("[ %s:%d ][%d][%d][%s][%s] "+format,
-		append([]interface{}{path.Base(fileName), lineNo, os.Getpid(), GetGoroutineID(), level, "none"},
-			args...)...)
+// loggerCore is shared by the logger and its child loggers(created by With)
+type loggerCore struct {
+	level   atomicLevel
+	sink    atomic.Value // sinkHolder
+	sampler atomic.Value // samplerHolder
+	// pendingSamplers are the samplers(of the core and WithSampling) which have suppressed logs, flushed by Flush
+	pendingSamplers sync.Map // *sampler => struct{}
 }
 
-func (l *defaultLogger) Debugf(format string, args ...any) {
-	if l != nil && l.level >= 5 { // >= Debug(5)
-		l.innerLog("Debug", format, args...)
+// sinkHolder make sure the atomic.Value always store the same type
+type sinkHolder struct {
+	Sink
+}
+
+func newLoggerCore(level Level, sink Sink) *loggerCore {
+	c := &loggerCore{
+		level: atomicLevel{v: uint32(level)},
 	}
+	c.setSink(sink)
+	c.sampler.Store(samplerHolder{})
+	return c
 }
 
-func (l *defaultLogger) Infof(format string, args ...any) {
-	if l != nil && l.level >= 4 { // >= Info(4)
-		l.innerLog("Info", format, args...)
+func (c *loggerCore) getSink() Sink {
+	return c.sink.Load().(sinkHolder).Sink
+}
+
+func (c *loggerCore) setSink(sink Sink) {
+	c.sink.Store(sinkHolder{sink})
+}
+
+// write writes the record to the sink, the hooks are fired by the caller before sampling
+func (c *loggerCore) write(r *Record) {
+	_ = c.getSink().WriteRecord(r)
+}
+
+func (l *defaultLogger) isEnabled(level Level) bool {
+	return l != nil && l.core.level.Load() >= level
+}
+
+func (l *defaultLogger) logWithPosf(level Level, fileName string, lineNo int, funName string, format string, args ...any) {
+	if l != nil && isEnabledAtPos(l.GetLevel(), level, fileName) {
+		l.outputWithPosf(level, fileName, lineNo, funName, format, args...)
 	}
 }
 
+// outputWithPosf output the log without check level, and then exit or panic for Fatal and Panic
+func (l *defaultLogger) outputWithPosf(level Level, fileName string, lineNo int, funName string, format string, args ...any) {
+	if l.isEnabled(level) || level > FatalLevel {
+		now := time.Now()
+		// the hooks see every record, include the ones dropped by sampling
+		var r *Record
+		if hasHooks(level) {
+			r = l.newRecord(now, level, fileName, lineNo, funName, format, args)
+			fireHooks(r)
+		}
+		if sp := l.getSampler(); sp != nil && level > FatalLevel {
+			allowed, suppressed := sp.check(level, fileName, lineNo, funName, now)
+			if suppressed > 0 {
+				l.writeSummary(level, fileName, lineNo, funName, suppressed)
+			}
+			if !allowed {
+				return
+			}
+		}
+		if r == nil {
+			r = l.newRecord(now, level, fileName, lineNo, funName, format, args)
+		}
+		l.core.write(r)
+	}
+	exitIfFatal(level, format, args...)
+}
+
+func (l *defaultLogger) newRecord(now time.Time, level Level, fileName string, lineNo int, funName string, format string, args []any) *Record {
+	gid := GetGoroutineID()
+	return &Record{
+		Time:        now,
+		Level:       level,
+		File:        fileName,
+		Line:        lineNo,
+		Function:    funName,
+		Pid:         _pid,
+		GoroutineID: gid,
+		Message:     formatMessage(format, args),
+		Fields:      resolveFields(mergeFields(l.fields, mdcFieldsOf(gid))),
+	}
+}
+
+func (l *defaultLogger) getSampler() *sampler {
+	if l.ownSampler {
+		return l.sampler
+	}
+	return l.core.sampler.Load().(samplerHolder).sampler
+}
+
+// writeSummary outputs the suppressed count of the callsite by sampling, it's not fired to the hooks,
+// which have seen the suppressed records already
+func (l *defaultLogger) writeSummary(level Level, fileName string, lineNo int, funName string, suppressed int64) {
+	l.core.write(&Record{
+		Time:        time.Now(),
+		Level:       level,
+		File:        fileName,
+		Line:        lineNo,
+		Function:    funName,
+		Pid:         _pid,
+		GoroutineID: GetGoroutineID(),
+		Message:     fmt.Sprintf("suppressed %d similar messages", suppressed),
+		Fields:      resolveFields(l.fields),
+	})
+}
+
+// logf is called by the method(example: Debugf) which is called by user directly, so skip is 3
+func (l *defaultLogger) logf(level Level, format string, args ...any) {
+	if l != nil && isEnabledAt(l.GetLevel(), level, 3) {
+		fileName, lineNo, funName := GetCallStackInfo(3)
+		l.outputWithPosf(level, fileName, lineNo, funName, format, args...)
+	}
+}
+
+// exitIfFatal calls `os.Exit(1)` for Fatal, and panic for Panic, flush the buffered logs before them
+func exitIfFatal(level Level, format string, args ...any) {
+	switch level {
+	case FatalLevel:
+		_ = Flush()
+		exitFunc(1)
+	case PanicLevel:
+		_ = Flush()
+		panic(formatMessage(format, args))
+	}
+}
+
+func (l *defaultLogger) Tracef(format string, args ...any) {
+	l.logf(TraceLevel, format, args...)
+}
+
+func (l *defaultLogger) Debugf(format string, args ...any) {
+	l.logf(DebugLevel, format, args...)
+}
+
+func (l *defaultLogger) Infof(format string, args ...any) {
+	l.logf(InfoLevel, format, args...)
+}
+
+func (l *defaultLogger) Warnf(format string, args ...any) {
+	l.logf(WarnLevel, format, args...)
+}
+
+func (l *defaultLogger) Errorf(format string, args ...any) {
+	l.logf(ErrorLevel, format, args...)
+}
+
+func (l *defaultLogger) Fatalf(format string, args ...any) {
+	l.logf(FatalLevel, format, args...)
+}
+
+func (l *defaultLogger) Panicf(format string, args ...any) {
+	l.logf(PanicLevel, format, args...)
+}
+
+func (l *defaultLogger) TraceExWithPosf(fileName string, lineNo int, funName string, format string, args ...any) {
+	l.logWithPosf(TraceLevel, fileName, lineNo, funName, format, args...)
+}
+
+func (l *defaultLogger) DebugExWithPosf(fileName string, lineNo int, funName string, format string, args ...any) {
+	l.logWithPosf(DebugLevel, fileName, lineNo, funName, format, args...)
+}
+
+func (l *defaultLogger) InfoExWithPosf(fileName string, lineNo int, funName string, format string, args ...any) {
+	l.logWithPosf(InfoLevel, fileName, lineNo, funName, format, args...)
+}
+
 func (l *defaultLogger) WarnExWithPosf(fileName string, lineNo int, funName string, format string, args ...interface{}) {
-	if l != nil && l.level >= 3 { // >= Warn(3)
-		log.Printf("[ %s:%d ][%d][%d][WARN][%s] "+format,
-			append([]interface{}{path.Base(fileName), lineNo, os.Getpid(), GetGoroutineID(), "none"},
-				args...)...)
+	l.logWithPosf(WarnLevel, fileName, lineNo, funName, format, args...)
+}
+
+func (l *defaultLogger) ErrorExWithPosf(fileName string, lineNo int, funName string, format string, args ...any) {
+	l.logWithPosf(ErrorLevel, fileName, lineNo, funName, format, args...)
+}
+
+func (l *defaultLogger) FatalExWithPosf(fileName string, lineNo int, funName string, format string, args ...any) {
+	l.logWithPosf(FatalLevel, fileName, lineNo, funName, format, args...)
+}
+
+func (l *defaultLogger) PanicExWithPosf(fileName string, lineNo int, funName string, format string, args ...any) {
+	l.logWithPosf(PanicLevel, fileName, lineNo, funName, format, args...)
+}
+
+// SetLevel set the level of the logger, the level is shared with parent and child loggers
+func (l *defaultLogger) SetLevel(level Level) {
+	l.core.level.Store(level)
+}
+
+func (l *defaultLogger) GetLevel() Level {
+	return l.core.level.Load()
+}
+
+func (l *defaultLogger) With(keyValues ...any) ILoggerEx {
+	return l.withFieldList(keyValuesToFields(keyValues))
+}
+
+func (l *defaultLogger) WithFields(fields Fields) ILoggerEx {
+	return l.withFieldList(mapToFields(fields))
+}
+
+func (l *defaultLogger) withFieldList(fields []Field) *defaultLogger {
+	return &defaultLogger{
+		core:       l.core,
+		fields:     mergeFields(l.fields, fields),
+		sampler:    l.sampler,
+		ownSampler: l.ownSampler,
+	}
+}
+
+func (l *defaultLogger) WithSampling(config *SamplingConfig) ILoggerEx {
+	child := l.withFieldList(nil)
+	child.sampler, child.ownSampler = newLoggerSampler(config, child), true
+	return child
+}
+
+// _defaultWriterSink is configured by SetEncoder/SetOutput
+var _defaultWriterSink = NewWriterSink(stdLogWriter{}, _stdLogEncoder)
+
+// _defaultLogger is the current logger until SetLoggerFactory is called
+var _defaultLogger = &defaultLogger{
+	core: newLoggerCore(DebugLevel, _defaultWriterSink), //default is 3(warn)
+}
+
+// NewLogger returns a builtin logger which writes to sink with its own level, it's independent of the default
+// logger(SetLevel, SetSink and SetSampling don't affect it), use SetLogger to make it the current logger.
+func NewLogger(level Level, sink Sink) ILoggerEx {
+	return &defaultLogger{core: newLoggerCore(level, sink)}
+}
+
+// _curLogger stores loggerHolder, which is replaced by SetLogger/SetLoggerFactory
+var _curLogger = newCurLogger(_defaultLogger)
+
+// _curLoggerGen is increased after the current logger is replaced, the named loggers cache by it
+var _curLoggerGen uint64
+
+func newCurLogger(logger ILoggerEx) *atomic.Value {
+	v := &atomic.Value{}
+	v.Store(loggerHolder{logger})
+	return v
+}
+
+// Flush flushes the sink if it's a Flusher
+func (l *defaultLogger) Flush() error {
+	l.core.pendingSamplers.Range(func(k, _ any) bool {
+		sp := k.(*sampler)
+		sp.takeSummaries(func(key siteKey, site *siteState, suppressed int64) {
+			sp.owner.writeSummary(site.level, key.file, key.line, site.funName, suppressed)
+		})
+		return true
+	})
+	if f, ok := l.core.getSink().(Flusher); ok {
+		return f.Flush()
 	}
+	return nil
 }
 
-var _curLogger ILogger = &defaultLogger{
-	level: 5, //default is 3(warn)
+// Close flushes and closes the sink if it's an io.Closer
+func (l *defaultLogger) Close() error {
+	sink := l.core.getSink()
+	if c, ok := sink.(io.Closer); ok {
+		return c.Close()
+	}
+	if f, ok := sink.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// SetEncoder set the encoder(example: NewJSONEncoder()) of the default WriterSink, default is TextEncoder.
+//
+// Notice: it's not used by the logger created by SetLoggerFactory, or after the sink is replaced by SetSink
+func SetEncoder(encoder Encoder) {
+	_defaultWriterSink.SetEncoder(encoder)
+}
+
+// SetOutput set the output of the default WriterSink, default is go std log(stderr), the default TextEncoder
+// adds the time when the output is not go std log
+func SetOutput(w io.Writer) {
+	_defaultWriterSink.SetOutput(w)
+}
+
+// SetSink replaces the sink of the default logger, example: NewAsyncSink, the default is a WriterSink
+func SetSink(sink Sink) {
+	_defaultLogger.core.setSink(sink)
+}
+
+// GetSink returns the sink of the default logger
+func GetSink() Sink {
+	return _defaultLogger.core.getSink()
+}
+
+// Flush flushes the buffered logs of the current logger(or its sink), it's called before Fatal exit
+func Flush() error {
+	if f, ok := curLogger().(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Close flushes and closes the current logger(or its sink), should be called before program exit
+func Close() error {
+	if c, ok := curLogger().(io.Closer); ok {
+		return c.Close()
+	}
+	return Flush()
+}
+
+var (
+	// _levelMu serializes SetLevel and SetLoggerFactory, so the new logger never misses the level
+	_levelMu     sync.Mutex
+	_globalLevel Level
+	_levelSet    bool
+)
+
+// SetLevel set the level of current logger, and the logger set by SetLoggerFactory later
+func SetLevel(level Level) {
+	_levelMu.Lock()
+	defer _levelMu.Unlock()
+	_globalLevel, _levelSet = level, true
+	curLogger().SetLevel(level)
+}
+
+// GetLevel return the level of current logger
+func GetLevel() Level {
+	return curLogger().GetLevel()
+}
+
+// outputLogger is implemented by the builtin loggers, which output the log without check the level again,
+// so the level overridden by vmodule can take effect.
+type outputLogger interface {
+	outputWithPosf(level Level, fileName string, lineNo int, funName string, format string, args ...any)
+}
+
+// logWithPosf is the common entry of package-level functions, which are called by user directly, so skip is 3
+func logWithPosf(l ILoggerEx, level Level, format string, args ...any) {
+	if !isEnabledAt(l.GetLevel(), level, 3) {
+		return
+	}
+	fileName, lineNo, funName := GetCallStackInfo(3)
+	outputWithPosf(l, level, fileName, lineNo, funName, format, args...)
+}
+
+// outputWithPosf outputs the log which is already checked level to the logger.
+// the args is copied before passed to the interface, so the variadic slice of the package-level functions
+// doesn't escape, and the disabled logs don't allocate.
+func outputWithPosf(l ILoggerEx, level Level, fileName string, lineNo int, funName string, format string, args ...any) {
+	dispatchWithPosf(l, level, fileName, lineNo, funName, format, copyArgs(args))
+}
+
+func copyArgs(args []any) []any {
+	if len(args) == 0 {
+		return nil
+	}
+	return append([]any(nil), args...)
+}
+
+func dispatchWithPosf(l ILoggerEx, level Level, fileName string, lineNo int, funName string, format string, args []any) {
+	if o, ok := l.(outputLogger); ok {
+		o.outputWithPosf(level, fileName, lineNo, funName, format, args...)
+		return
+	}
+	switch level {
+	case TraceLevel:
+		l.TraceExWithPosf(fileName, lineNo, funName, format, args...)
+	case DebugLevel:
+		l.DebugExWithPosf(fileName, lineNo, funName, format, args...)
+	case InfoLevel:
+		l.InfoExWithPosf(fileName, lineNo, funName, format, args...)
+	case WarnLevel:
+		l.WarnExWithPosf(fileName, lineNo, funName, format, args...)
+	case ErrorLevel:
+		l.ErrorExWithPosf(fileName, lineNo, funName, format, args...)
+	case FatalLevel:
+		l.FatalExWithPosf(fileName, lineNo, funName, format, args...)
+	case PanicLevel:
+		l.PanicExWithPosf(fileName, lineNo, funName, format, args...)
+	}
+}
+
+func Tracef(format string, args ...any) {
+	logWithPosf(curLogger(), TraceLevel, format, args...)
 }
 
 func Debugf(format string, args ...any) {
-	_curLogger.Debugf(format, args...)
+	l := curLogger()
+	if c, ok := l.(*compatLogger); ok {
+		// call the simple ILogger directly, keep the same call depth as before
+		if isEnabledAt(c.GetLevel(), DebugLevel, 2) {
+			prepared, preparedArgs := c.prepare(format, args)
+			c.ILogger.Debugf(prepared, preparedArgs...)
+		}
+		return
+	}
+	logWithPosf(l, DebugLevel, format, args...)
 }
 
 func Infof(format string, args ...any) {
-	_curLogger.Infof(format, args...)
+	l := curLogger()
+	if c, ok := l.(*compatLogger); ok {
+		if isEnabledAt(c.GetLevel(), InfoLevel, 2) {
+			prepared, preparedArgs := c.prepare(format, args)
+			c.ILogger.Infof(prepared, preparedArgs...)
+		}
+		return
+	}
+	logWithPosf(l, InfoLevel, format, args...)
+}
+
+func Warnf(format string, args ...any) {
+	logWithPosf(curLogger(), WarnLevel, format, args...)
+}
+
+func Errorf(format string, args ...any) {
+	logWithPosf(curLogger(), ErrorLevel, format, args...)
+}
+
+// Fatalf logs and then calls `os.Exit(1)`
+func Fatalf(format string, args ...any) {
+	logWithPosf(curLogger(), FatalLevel, format, args...)
+}
+
+// Panicf logs and then calls panic with the message
+func Panicf(format string, args ...any) {
+	logWithPosf(curLogger(), PanicLevel, format, args...)
+}
+
+func TraceExWithPosf(fileName string, lineNo int, funName string, format string, args ...any) {
+	curLogger().TraceExWithPosf(fileName, lineNo, funName, format, args...)
+}
+
+func DebugExWithPosf(fileName string, lineNo int, funName string, format string, args ...any) {
+	curLogger().DebugExWithPosf(fileName, lineNo, funName, format, args...)
+}
+
+func InfoExWithPosf(fileName string, lineNo int, funName string, format string, args ...any) {
+	curLogger().InfoExWithPosf(fileName, lineNo, funName, format, args...)
 }
 
 func WarnExWithPosf(fileName string, lineNo int, funName string, format string, args ...any) {
-	_curLogger.WarnExWithPosf(fileName, lineNo, funName, format, args...)
-}
\ No newline at end of file
+	curLogger().WarnExWithPosf(fileName, lineNo, funName, format, args...)
+}
+
+func ErrorExWithPosf(fileName string, lineNo int, funName string, format string, args ...any) {
+	curLogger().ErrorExWithPosf(fileName, lineNo, funName, format, args...)
+}
+
+func FatalExWithPosf(fileName string, lineNo int, funName string, format string, args ...any) {
+	curLogger().FatalExWithPosf(fileName, lineNo, funName, format, args...)
+}
+
+func PanicExWithPosf(fileName string, lineNo int, funName string, format string, args ...any) {
+	curLogger().PanicExWithPosf(fileName, lineNo, funName, format, args...)
+}
+
+// compatLogger adapts the simple ILogger(only Debugf, Infof and WarnExWithPosf) to ILoggerEx:
+//   - Trace goes to Debugf, Error/Fatal/Panic go to WarnExWithPosf with a level mark
+//   - the level is filtered here, the simple ILogger can still do its own filter
+//   - the fields are passed to FieldLogger, or formatted as prefix of the message
+type compatLogger struct {
+	ILogger
+	level *atomicLevel
+
+	// fieldsPrefix is used when ILogger is not a FieldLogger, already escaped for format
+	fieldsPrefix string
+	fields       []Field
+}
+
+func toLoggerEx(logger ILogger) ILoggerEx {
+	if l, ok := logger.(ILoggerEx); ok {
+		return l
+	}
+	return &compatLogger{ILogger: logger, level: newAtomicLevel(TraceLevel)}
+}
+
+func (c *compatLogger) logWithPosf(level Level, fileName string, lineNo int, funName string, format string, args ...any) {
+	if isEnabledAtPos(c.GetLevel(), level, fileName) {
+		c.outputWithPosf(level, fileName, lineNo, funName, format, args...)
+	}
+}
+
+func (c *compatLogger) outputWithPosf(level Level, fileName string, lineNo int, funName string, format string, args ...any) {
+	if c.GetLevel() >= level || level > FatalLevel {
+		format, args := c.prepare(format, args)
+		switch level {
+		case TraceLevel, DebugLevel:
+			c.ILogger.Debugf(format, args...)
+		case InfoLevel:
+			c.ILogger.Infof(format, args...)
+		case WarnLevel:
+			c.ILogger.WarnExWithPosf(fileName, lineNo, funName, format, args...)
+		default:
+			c.ILogger.WarnExWithPosf(fileName, lineNo, funName, "["+level.String()+"] "+format, args...)
+		}
+	}
+	exitIfFatal(level, format, args...)
+}
+
+// prepare returns the format with fields prefix and the resolved args, the message is formatted here
+// when there are redact patterns, so the simple ILogger never gets the secret.
+// the args is always copied, so the variadic slice of caller doesn't escape.
+func (c *compatLogger) prepare(format string, args []any) (string, []any) {
+	format = c.fieldsPrefix + format
+	if config := loadRedactConfig(); config != nil && len(config.patterns) > 0 {
+		return "%s", []any{formatMessage(format, args)}
+	}
+	return format, resolveArgs(copyArgs(args))
+}
+
+func (c *compatLogger) logf(level Level, format string, args ...any) {
+	if isEnabledAt(c.GetLevel(), level, 3) {
+		fileName, lineNo, funName := GetCallStackInfo(3)
+		c.outputWithPosf(level, fileName, lineNo, funName, format, args...)
+	}
+}
+
+func (c *compatLogger) Debugf(format string, args ...any) {
+	if isEnabledAt(c.GetLevel(), DebugLevel, 2) {
+		prepared, preparedArgs := c.prepare(format, args)
+		c.ILogger.Debugf(prepared, preparedArgs...)
+	}
+}
+
+func (c *compatLogger) Infof(format string, args ...any) {
+	if isEnabledAt(c.GetLevel(), InfoLevel, 2) {
+		prepared, preparedArgs := c.prepare(format, args)
+		c.ILogger.Infof(prepared, preparedArgs...)
+	}
+}
+
+func (c *compatLogger) Tracef(format string, args ...any) {
+	c.logf(TraceLevel, format, args...)
+}
+
+func (c *compatLogger) Warnf(format string, args ...any) {
+	c.logf(WarnLevel, format, args...)
+}
+
+func (c *compatLogger) Errorf(format string, args ...any) {
+	c.logf(ErrorLevel, format, args...)
+}
+
+func (c *compatLogger) Fatalf(format string, args ...any) {
+	c.logf(FatalLevel, format, args...)
+}
+
+func (c *compatLogger) Panicf(format string, args ...any) {
+	c.logf(PanicLevel, format, args...)
+}
+
+func (c *compatLogger) TraceExWithPosf(fileName string, lineNo int, funName string, format string, args ...any) {
+	c.logWithPosf(TraceLevel, fileName, lineNo, funName, format, args...)
+}
+
+func (c *compatLogger) DebugExWithPosf(fileName string, lineNo int, funName string, format string, args ...any) {
+	c.logWithPosf(DebugLevel, fileName, lineNo, funName, format, args...)
+}
+
+func (c *compatLogger) InfoExWithPosf(fileName string, lineNo int, funName string, format string, args ...any) {
+	c.logWithPosf(InfoLevel, fileName, lineNo, funName, format, args...)
+}
+
+func (c *compatLogger) WarnExWithPosf(fileName string, lineNo int, funName string, format string, args ...any) {
+	c.logWithPosf(WarnLevel, fileName, lineNo, funName, format, args...)
+}
+
+func (c *compatLogger) ErrorExWithPosf(fileName string, lineNo int, funName string, format string, args ...any) {
+	c.logWithPosf(ErrorLevel, fileName, lineNo, funName, format, args...)
+}
+
+func (c *compatLogger) FatalExWithPosf(fileName string, lineNo int, funName string, format string, args ...any) {
+	c.logWithPosf(FatalLevel, fileName, lineNo, funName, format, args...)
+}
+
+func (c *compatLogger) PanicExWithPosf(fileName string, lineNo int, funName string, format string, args ...any) {
+	c.logWithPosf(PanicLevel, fileName, lineNo, funName, format, args...)
+}
+
+func (c *compatLogger) SetLevel(level Level) {
+	c.level.Store(level)
+}
+
+func (c *compatLogger) GetLevel() Level {
+	return c.level.Load()
+}
+
+func (c *compatLogger) With(keyValues ...any) ILoggerEx {
+	return c.withFieldList(keyValuesToFields(keyValues))
+}
+
+func (c *compatLogger) WithFields(fields Fields) ILoggerEx {
+	return c.withFieldList(mapToFields(fields))
+}
+
+func (c *compatLogger) withFieldList(fields []Field) *compatLogger {
+	if fl, ok := c.ILogger.(FieldLogger); ok {
+		return &compatLogger{ILogger: fl.WithFieldList(resolveFields(fields)), level: c.level}
+	}
+	allFields := mergeFields(c.fields, fields)
+	return &compatLogger{
+		ILogger:      c.ILogger,
+		level:        c.level,
+		fieldsPrefix: "[" + strings.ReplaceAll(formatFieldsText(resolveFields(allFields)), "%", "%%") + "] ",
+		fields:       allFields,
+	}
+}
@@ -0,0 +1,222 @@
+package flog
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordLogger is the builtin logger which keeps the records, installed as the current logger by useRecordLogger
+type recordLogger struct {
+	*defaultLogger
+	sink *recordSink
+}
+
+type recordSink struct {
+	mu      sync.Mutex
+	records []Record
+}
+
+func (s *recordSink) WriteRecord(r *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record := *r
+	record.Fields = append([]Field(nil), r.Fields...)
+	s.records = append(s.records, record)
+	return nil
+}
+
+// useRecordLogger installs the recordLogger(level is TraceLevel) until the test finishes
+func useRecordLogger(t *testing.T) *recordLogger {
+	sink := &recordSink{}
+	rl := &recordLogger{defaultLogger: &defaultLogger{core: newLoggerCore(TraceLevel, sink)}, sink: sink}
+	prev := SetLogger(rl)
+	t.Cleanup(func() { SetLogger(prev) })
+	return rl
+}
+
+func (rl *recordLogger) Records() []Record {
+	rl.sink.mu.Lock()
+	defer rl.sink.mu.Unlock()
+	return append([]Record(nil), rl.sink.records...)
+}
+
+// Count returns the count of records at level whose message contains substr
+func (rl *recordLogger) Count(level Level, substr string) int {
+	count := 0
+	for _, r := range rl.Records() {
+		if r.Level == level && strings.Contains(r.Message, substr) {
+			count++
+		}
+	}
+	return count
+}
+
+// Reset clears the kept records
+func (rl *recordLogger) Reset() {
+	rl.sink.mu.Lock()
+	defer rl.sink.mu.Unlock()
+	rl.sink.records = nil
+}
+
+type simpleLogger struct {
+	lines []string
+}
+
+func (s *simpleLogger) Debugf(format string, args ...any) {
+	s.lines = append(s.lines, "D:"+fmt.Sprintf(format, args...))
+}
+
+func (s *simpleLogger) Infof(format string, args ...any) {
+	s.lines = append(s.lines, "I:"+fmt.Sprintf(format, args...))
+}
+
+func (s *simpleLogger) WarnExWithPosf(fileName string, lineNo int, funName string, format string, args ...any) {
+	s.lines = append(s.lines, "W:"+fmt.Sprintf(format, args...))
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, level := range AllLevels {
+		parsed, err := ParseLevel(strings.ToLower(level.String()))
+		if err != nil || parsed != level {
+			t.Errorf("ParseLevel(%s)=%s, err=%v", level, parsed, err)
+		}
+	}
+	if level, err := ParseLevel("warning"); err != nil || level != WarnLevel {
+		t.Errorf("ParseLevel(warning)=%s, err=%v", level, err)
+	}
+	if level, err := ParseLevel("5"); err != nil || level != DebugLevel {
+		t.Errorf("ParseLevel(5)=%s, err=%v", level, err)
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Errorf("ParseLevel(verbose) should fail")
+	}
+
+	var level Level
+	if err := level.UnmarshalText([]byte("Error")); err != nil || level != ErrorLevel {
+		t.Errorf("UnmarshalText=%s, err=%v", level, err)
+	}
+}
+
+func TestCompatLogger(t *testing.T) {
+	oldLogger := curLogger()
+	defer SetLogger(oldLogger)
+
+	simple := &simpleLogger{}
+	SetLoggerFactory(func() ILogger {
+		return simple
+	})
+
+	SetLevel(InfoLevel)
+	Tracef("trace %d", 1)
+	Debugf("debug %d", 2)
+	Infof("info %d", 3)
+	Warnf("warn %d", 4)
+	Errorf("error %d", 5)
+
+	expected := []string{"I:info 3", "W:warn 4", "W:[ERROR] error 5"}
+	if fmt.Sprint(simple.lines) != fmt.Sprint(expected) {
+		t.Errorf("lines=%q, expected=%q", simple.lines, expected)
+	}
+}
+
+func TestDefaultLoggerLevel(t *testing.T) {
+	var buf bytes.Buffer
+	oldLevel := GetLevel()
+	oldExit := exitFunc
+	defer func() {
+		log.SetOutput(os.Stderr)
+		SetLevel(oldLevel)
+		exitFunc = oldExit
+	}()
+	log.SetOutput(&buf)
+
+	exitCode := -1
+	exitFunc = func(code int) { exitCode = code }
+
+	SetLevel(WarnLevel)
+	Debugf("should not output")
+	Warnf("warn output")
+	Fatalf("fatal output")
+
+	output := buf.String()
+	if strings.Contains(output, "should not output") {
+		t.Errorf("debug log should be filtered, output=%s", output)
+	}
+	if !strings.Contains(output, "logger_test.go") || !strings.Contains(output, "[WARN]") ||
+		!strings.Contains(output, "warn output") {
+		t.Errorf("wrong warn output=%s", output)
+	}
+	if exitCode != 1 || !strings.Contains(output, "[FATAL]") {
+		t.Errorf("fatal should exit with 1, exitCode=%d, output=%s", exitCode, output)
+	}
+
+	defer func() {
+		if r := recover(); r != "panic output 1" {
+			t.Errorf("wrong panic message: %v", r)
+		}
+	}()
+	Panicf("panic output %d", 1)
+}
+
+func TestDefaultLoggerStdLogFlags(t *testing.T) {
+	var buf bytes.Buffer
+	oldLevel, oldFlags, oldPrefix := GetLevel(), log.Flags(), log.Prefix()
+	defer func() {
+		log.SetOutput(os.Stderr)
+		log.SetFlags(oldFlags)
+		log.SetPrefix(oldPrefix)
+		SetLevel(oldLevel)
+	}()
+	log.SetOutput(&buf)
+	log.SetFlags(0)
+	log.SetPrefix("app: ")
+
+	SetLevel(DebugLevel)
+	Debugf("debug %d", 1)
+	Infof("info %d", 2)
+	Warnf("warn %d", 3)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	tokens := []string{"][Debug][none] debug 1", "][Info][none] info 2", "][WARN][none] warn 3"}
+	if len(lines) != len(tokens) {
+		t.Fatalf("wrong output=%s", buf.String())
+	}
+	for i, line := range lines {
+		// no time since the flags of go std log is 0
+		if !strings.HasPrefix(line, "app: [ logger_test.go:") || !strings.HasSuffix(line, tokens[i]) {
+			t.Errorf("line %d=%q, should has the prefix of go std log and end with %q", i, line, tokens[i])
+		}
+	}
+}
+
+func TestSetLoggerConcurrent(t *testing.T) {
+	oldLogger := curLogger()
+	defer SetLogger(oldLogger)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				SetLoggerFactory(func() ILogger { return &simpleLogger{} })
+				SetLogger(nil)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_ = GetLevel()
+			}
+		}()
+	}
+	wg.Wait()
+	if SetLogger(oldLogger) != ILoggerEx(_defaultLogger) {
+		t.Errorf("nil should set the default logger")
+	}
+}
@@ -0,0 +1,184 @@
+package flog
+
+import (
+	"bytes"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// MDC(mapped diagnostic context) stores the key/values for the current goroutine(by GetGoroutineID),
+// the default logger outputs them as fields in every record of the goroutine, example:
+//
+//	flog.MDCPut("upload", id)
+//	defer flog.MDCClear()
+//	flog.Go(func() { flog.Infof("child goroutine also has the upload field") })
+//
+// the goroutine which calls MDCPut should call MDCClear before exit, otherwise the entry is removed by
+// MDCPrune, which is triggered automatically when the count of entries grows too much.
+
+// mdcPruneThreshold is the min count of entries to trigger auto prune
+const mdcPruneThreshold = 1024
+
+var (
+	_mdcMu sync.RWMutex
+	// _mdc is goroutine id => fields, the fields slice is copy on write, so can be read without lock
+	_mdc = make(map[uint64][]Field)
+	// _mdcCount is len(_mdc), so the logger can skip the lookup when there is no MDC
+	_mdcCount int32
+	// _mdcPruneAt is the count to trigger next auto prune
+	_mdcPruneAt = mdcPruneThreshold
+	_mdcPruning int32
+)
+
+// MDCPut sets the key/value for the current goroutine
+func MDCPut(key string, value any) {
+	gid := GetGoroutineID()
+	_mdcMu.Lock()
+	old := _mdc[gid]
+	fields := make([]Field, 0, len(old)+1)
+	for _, f := range old {
+		if f.Key != key {
+			fields = append(fields, f)
+		}
+	}
+	_mdc[gid] = append(fields, Field{Key: key, Value: value})
+	needPrune := len(_mdc) >= _mdcPruneAt
+	atomic.StoreInt32(&_mdcCount, int32(len(_mdc)))
+	_mdcMu.Unlock()
+
+	if needPrune && atomic.CompareAndSwapInt32(&_mdcPruning, 0, 1) {
+		go func() {
+			defer atomic.StoreInt32(&_mdcPruning, 0)
+			MDCPrune()
+		}()
+	}
+}
+
+// MDCGet returns the value of key for the current goroutine
+func MDCGet(key string) (any, bool) {
+	for _, f := range mdcFieldsOf(GetGoroutineID()) {
+		if f.Key == key {
+			return f.Value, true
+		}
+	}
+	return nil, false
+}
+
+// MDCRemove removes the key for the current goroutine
+func MDCRemove(key string) {
+	gid := GetGoroutineID()
+	_mdcMu.Lock()
+	defer _mdcMu.Unlock()
+	old, ok := _mdc[gid]
+	if !ok {
+		return
+	}
+	fields := make([]Field, 0, len(old))
+	for _, f := range old {
+		if f.Key != key {
+			fields = append(fields, f)
+		}
+	}
+	if len(fields) == 0 {
+		delete(_mdc, gid)
+	} else {
+		_mdc[gid] = fields
+	}
+	atomic.StoreInt32(&_mdcCount, int32(len(_mdc)))
+}
+
+// MDCClear removes all the key/values of the current goroutine
+func MDCClear() {
+	mdcSet(GetGoroutineID(), nil)
+}
+
+// MDCCopy returns a copy of the key/values of the current goroutine
+func MDCCopy() map[string]any {
+	fields := mdcFieldsOf(GetGoroutineID())
+	result := make(map[string]any, len(fields))
+	for _, f := range fields {
+		result[f.Key] = f.Value
+	}
+	return result
+}
+
+func mdcSet(gid uint64, fields []Field) {
+	_mdcMu.Lock()
+	defer _mdcMu.Unlock()
+	if len(fields) == 0 {
+		delete(_mdc, gid)
+	} else {
+		_mdc[gid] = fields
+	}
+	atomic.StoreInt32(&_mdcCount, int32(len(_mdc)))
+}
+
+// mdcFieldsOf returns the fields of the goroutine, the result should not be modified
+func mdcFieldsOf(gid uint64) []Field {
+	if atomic.LoadInt32(&_mdcCount) == 0 {
+		return nil
+	}
+	_mdcMu.RLock()
+	defer _mdcMu.RUnlock()
+	return _mdc[gid]
+}
+
+// Go starts a goroutine which inherits the MDC of the current goroutine,
+// and clears the MDC of the new goroutine after fn returns.
+func Go(fn func()) {
+	parent := mdcFieldsOf(GetGoroutineID())
+	go func() {
+		gid := GetGoroutineID()
+		if len(parent) > 0 {
+			mdcSet(gid, parent)
+		}
+		defer mdcSet(gid, nil)
+		fn()
+	}()
+}
+
+// MDCPrune removes the entries of the finished goroutines, it calls runtime.Stack for all goroutines,
+// which stops the world, so it's only triggered automatically when the entries double.
+func MDCPrune() {
+	// take the snapshot with the lock, otherwise the MDC set by the goroutine started after the snapshot is removed
+	_mdcMu.Lock()
+	defer _mdcMu.Unlock()
+	alive := aliveGoroutineIDs()
+	for gid := range _mdc {
+		if !alive[gid] {
+			delete(_mdc, gid)
+		}
+	}
+	atomic.StoreInt32(&_mdcCount, int32(len(_mdc)))
+	_mdcPruneAt = 2 * len(_mdc)
+	if _mdcPruneAt < mdcPruneThreshold {
+		_mdcPruneAt = mdcPruneThreshold
+	}
+}
+
+// aliveGoroutineIDs parses all the "goroutine 123 [" lines from runtime.Stack(all=true)
+func aliveGoroutineIDs() map[uint64]bool {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	alive := make(map[uint64]bool)
+	for _, line := range bytes.Split(buf, []byte("\n")) {
+		if !bytes.HasPrefix(line, goroutineSpace) {
+			continue
+		}
+		line = line[len(goroutineSpace):]
+		if i := bytes.IndexByte(line, ' '); i > 0 {
+			if gid, err := parseUintBytes(line[:i], 10, 64); err == nil {
+				alive[gid] = true
+			}
+		}
+	}
+	return alive
+}
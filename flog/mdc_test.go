@@ -0,0 +1,119 @@
+package flog
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMDC(t *testing.T) {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	defer SetOutput(stdLogWriter{})
+
+	MDCPut("upload", "u1")
+	MDCPut("part", 1)
+	MDCPut("part", 2)
+	defer MDCClear()
+	if value, ok := MDCGet("part"); !ok || value != 2 {
+		t.Errorf("wrong part value %v", value)
+	}
+
+	Warnf("parent")
+	var wg sync.WaitGroup
+	wg.Add(1)
+	Go(func() {
+		defer wg.Done()
+		MDCPut("child", true)
+		Warnf("child")
+	})
+	wg.Wait()
+	MDCRemove("part")
+	Warnf("removed")
+	if copied := MDCCopy(); len(copied) != 1 || copied["upload"] != "u1" {
+		t.Errorf("wrong MDC copy %v", copied)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	expected := []string{"[upload=u1 part=2] parent", "[upload=u1 part=2 child=true] child", "[upload=u1] removed"}
+	if len(lines) != len(expected) {
+		t.Fatalf("wrong lines: %q", lines)
+	}
+	for i, line := range lines {
+		if !strings.Contains(line, expected[i]) {
+			t.Errorf("line %d: %s, expected %s", i, line, expected[i])
+		}
+	}
+}
+
+func TestMDCCleanup(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(2)
+		Go(func() {
+			defer wg.Done()
+			MDCPut("k", "v")
+		})
+		// leak the MDC entry without MDCClear
+		go func() {
+			defer wg.Done()
+			MDCPut("leak", "v")
+		}()
+	}
+	wg.Wait()
+
+	// the goroutines may not exit immediately after wg.Done
+	count := -1
+	for i := 0; i < 100 && count != 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		MDCPrune()
+		_mdcMu.RLock()
+		count = len(_mdc)
+		_mdcMu.RUnlock()
+	}
+	if count != 0 {
+		t.Errorf("MDC entries of finished goroutines should be removed, count=%d", count)
+	}
+}
+
+func TestMDCPruneKeepsNewGoroutines(t *testing.T) {
+	stop := make(chan struct{})
+	var pruneWg sync.WaitGroup
+	pruneWg.Add(1)
+	go func() {
+		defer pruneWg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				MDCPrune()
+			}
+		}
+	}()
+
+	// the goroutines start and set MDC while pruning, their MDC should never be removed
+	var wg sync.WaitGroup
+	lost := int32(0)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer MDCClear()
+			MDCPut("k", "v")
+			time.Sleep(time.Millisecond)
+			if _, ok := MDCGet("k"); !ok {
+				atomic.AddInt32(&lost, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	close(stop)
+	pruneWg.Wait()
+	if lost != 0 {
+		t.Errorf("%d MDC of alive goroutines are removed", lost)
+	}
+}
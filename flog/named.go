@@ -0,0 +1,276 @@
+package flog
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// LoggerNameField is the field added by NameFieldFactory and the named logger with its own sink
+const LoggerNameField = "logger"
+
+// NamedLoggerFactory creates the logger for the name(example: "ioext"), it's called lazily when the named
+// logger is used first time after the factory is set.
+type NamedLoggerFactory func(name string) ILogger
+
+// namedFactoryHolder is stored as pointer, so the named loggers know the factory changed by comparing it
+type namedFactoryHolder struct {
+	factory NamedLoggerFactory
+}
+
+// namedState is the config and resolved logger of one name, shared by GetLogger(name)
+type namedState struct {
+	name string
+
+	level    atomicLevel
+	hasLevel int32
+
+	// sinkLogger is the builtin logger writes to the sink set by SetLoggerSink, nil means not set
+	sinkLogger atomic.Value // loggerHolder
+
+	// cache is the resolved logger and what it's resolved from
+	cache atomic.Value // namedCache
+}
+
+// namedCache is valid while the factory and the current logger are not changed, the current logger is not
+// compared directly because it may be not comparable
+type namedCache struct {
+	holder *namedFactoryHolder
+	gen    uint64 // _curLoggerGen
+	logger ILoggerEx
+}
+
+var (
+	_namedMu      sync.Mutex
+	_namedStates  = make(map[string]*namedState)
+	_namedFactory atomic.Value // *namedFactoryHolder
+)
+
+func init() {
+	_namedFactory.Store((*namedFactoryHolder)(nil))
+}
+
+// SetNamedLoggerFactory sets the factory of named loggers, nil means the named loggers use the current logger
+// (set by SetLoggerFactory) directly, which is the default. use NameFieldFactory to add the "logger" field.
+func SetNamedLoggerFactory(factory NamedLoggerFactory) {
+	if factory == nil {
+		_namedFactory.Store((*namedFactoryHolder)(nil))
+		return
+	}
+	_namedFactory.Store(&namedFactoryHolder{factory: factory})
+}
+
+// NameFieldFactory is the NamedLoggerFactory which adds field "logger=name" to the current logger, example:
+//
+//	flog.SetNamedLoggerFactory(flog.NameFieldFactory)
+func NameFieldFactory(name string) ILogger {
+	return curLogger().With(LoggerNameField, name)
+}
+
+// GetLogger returns the named logger, which resolves the real logger lazily every time it's used:
+//   - the builtin logger writes to the sink set by SetLoggerSink(name)
+//   - or the logger created by the factory set by SetNamedLoggerFactory, it's created again when the factory
+//     or the current logger is changed
+//   - or the current logger(set by SetLoggerFactory)
+//
+// so it can be stored in package variable before the logger is configured, example:
+//
+//	var logger = flog.GetLogger("ioext")
+//
+// the level can be set by SetLoggerLevel(name) or logger.SetLevel, otherwise it's the level of the real logger.
+// Notice: the child logger returned by With/WithFields is resolved when it's created.
+func GetLogger(name string) ILoggerEx {
+	return &namedLogger{state: getNamedState(name)}
+}
+
+func getNamedState(name string) *namedState {
+	_namedMu.Lock()
+	defer _namedMu.Unlock()
+	state, ok := _namedStates[name]
+	if !ok {
+		state = &namedState{name: name}
+		state.sinkLogger.Store(loggerHolder{})
+		state.cache.Store(namedCache{})
+		_namedStates[name] = state
+	}
+	return state
+}
+
+// SetLoggerLevel sets the level of the named logger, which overrides the level of the real logger
+func SetLoggerLevel(name string, level Level) {
+	state := getNamedState(name)
+	state.level.Store(level)
+	atomic.StoreInt32(&state.hasLevel, 1)
+}
+
+// ResetLoggerLevel removes the level set by SetLoggerLevel, so the named logger uses the level of the real logger
+func ResetLoggerLevel(name string) {
+	atomic.StoreInt32(&getNamedState(name).hasLevel, 0)
+}
+
+// SetLoggerSink sets the sink of the named logger, then it writes to the sink by the builtin logger,
+// nil means write to the resolved logger again. the level is the global level if not set by SetLoggerLevel.
+func SetLoggerSink(name string, sink Sink) {
+	state := getNamedState(name)
+	if sink == nil {
+		state.sinkLogger.Store(loggerHolder{})
+		return
+	}
+	logger := &defaultLogger{core: newLoggerCore(TraceLevel, sink)}
+	state.sinkLogger.Store(loggerHolder{logger.withFieldList([]Field{{Key: LoggerNameField, Value: name}})})
+}
+
+// resolve returns the real logger and whether the level is decided by the global level
+func (s *namedState) resolve() (ILoggerEx, bool) {
+	if logger := s.sinkLogger.Load().(loggerHolder).ILoggerEx; logger != nil {
+		return logger, true
+	}
+
+	holder := _namedFactory.Load().(*namedFactoryHolder)
+	if holder == nil {
+		return curLogger(), false
+	}
+	// load the generation before the factory reads the current logger, so a concurrent SetLogger only makes
+	// the cache stale
+	gen := atomic.LoadUint64(&_curLoggerGen)
+	if cache := s.cache.Load().(namedCache); cache.holder == holder && cache.gen == gen {
+		return cache.logger, false
+	}
+	logger := toLoggerEx(holder.factory(s.name))
+	s.cache.Store(namedCache{holder: holder, gen: gen, logger: logger})
+	return logger, false
+}
+
+// namedLogger is returned by GetLogger
+type namedLogger struct {
+	state *namedState
+}
+
+// target returns the real logger and the level of the named logger
+func (n *namedLogger) target() (ILoggerEx, Level) {
+	logger, useGlobal := n.state.resolve()
+	switch {
+	case atomic.LoadInt32(&n.state.hasLevel) != 0:
+		return logger, n.state.level.Load()
+	case useGlobal:
+		return logger, GetLevel()
+	default:
+		return logger, logger.GetLevel()
+	}
+}
+
+// logf is called by the method(example: Debugf) which is called by user directly, so skip is 3
+func (n *namedLogger) logf(level Level, format string, args ...any) {
+	logger, loggerLevel := n.target()
+	if !isEnabledAt(loggerLevel, level, 3) {
+		return
+	}
+	fileName, lineNo, funName := GetCallStackInfo(3)
+	outputWithPosf(logger, level, fileName, lineNo, funName, format, args...)
+}
+
+func (n *namedLogger) logWithPosf(level Level, fileName string, lineNo int, funName string, format string, args ...any) {
+	logger, loggerLevel := n.target()
+	if isEnabledAtPos(loggerLevel, level, fileName) {
+		outputWithPosf(logger, level, fileName, lineNo, funName, format, args...)
+	}
+}
+
+// outputWithPosf implements outputLogger, so the package-level functions can use the named logger
+func (n *namedLogger) outputWithPosf(level Level, fileName string, lineNo int, funName string, format string, args ...any) {
+	logger, _ := n.target()
+	outputWithPosf(logger, level, fileName, lineNo, funName, format, args...)
+}
+
+func (n *namedLogger) Tracef(format string, args ...any) {
+	n.logf(TraceLevel, format, args...)
+}
+
+func (n *namedLogger) Debugf(format string, args ...any) {
+	logger, loggerLevel := n.target()
+	if c, ok := logger.(*compatLogger); ok {
+		// call the simple ILogger directly, keep the same call depth as flog.Debugf
+		if isEnabledAt(loggerLevel, DebugLevel, 2) {
+			prepared, preparedArgs := c.prepare(format, args)
+			c.ILogger.Debugf(prepared, preparedArgs...)
+		}
+		return
+	}
+	n.logf(DebugLevel, format, args...)
+}
+
+func (n *namedLogger) Infof(format string, args ...any) {
+	logger, loggerLevel := n.target()
+	if c, ok := logger.(*compatLogger); ok {
+		if isEnabledAt(loggerLevel, InfoLevel, 2) {
+			prepared, preparedArgs := c.prepare(format, args)
+			c.ILogger.Infof(prepared, preparedArgs...)
+		}
+		return
+	}
+	n.logf(InfoLevel, format, args...)
+}
+
+func (n *namedLogger) Warnf(format string, args ...any) {
+	n.logf(WarnLevel, format, args...)
+}
+
+func (n *namedLogger) Errorf(format string, args ...any) {
+	n.logf(ErrorLevel, format, args...)
+}
+
+func (n *namedLogger) Fatalf(format string, args ...any) {
+	n.logf(FatalLevel, format, args...)
+}
+
+func (n *namedLogger) Panicf(format string, args ...any) {
+	n.logf(PanicLevel, format, args...)
+}
+
+func (n *namedLogger) TraceExWithPosf(fileName string, lineNo int, funName string, format string, args ...any) {
+	n.logWithPosf(TraceLevel, fileName, lineNo, funName, format, args...)
+}
+
+func (n *namedLogger) DebugExWithPosf(fileName string, lineNo int, funName string, format string, args ...any) {
+	n.logWithPosf(DebugLevel, fileName, lineNo, funName, format, args...)
+}
+
+func (n *namedLogger) InfoExWithPosf(fileName string, lineNo int, funName string, format string, args ...any) {
+	n.logWithPosf(InfoLevel, fileName, lineNo, funName, format, args...)
+}
+
+func (n *namedLogger) WarnExWithPosf(fileName string, lineNo int, funName string, format string, args ...any) {
+	n.logWithPosf(WarnLevel, fileName, lineNo, funName, format, args...)
+}
+
+func (n *namedLogger) ErrorExWithPosf(fileName string, lineNo int, funName string, format string, args ...any) {
+	n.logWithPosf(ErrorLevel, fileName, lineNo, funName, format, args...)
+}
+
+func (n *namedLogger) FatalExWithPosf(fileName string, lineNo int, funName string, format string, args ...any) {
+	n.logWithPosf(FatalLevel, fileName, lineNo, funName, format, args...)
+}
+
+func (n *namedLogger) PanicExWithPosf(fileName string, lineNo int, funName string, format string, args ...any) {
+	n.logWithPosf(PanicLevel, fileName, lineNo, funName, format, args...)
+}
+
+// SetLevel is same as SetLoggerLevel(name, level)
+func (n *namedLogger) SetLevel(level Level) {
+	n.state.level.Store(level)
+	atomic.StoreInt32(&n.state.hasLevel, 1)
+}
+
+func (n *namedLogger) GetLevel() Level {
+	_, level := n.target()
+	return level
+}
+
+func (n *namedLogger) With(keyValues ...any) ILoggerEx {
+	logger, _ := n.target()
+	return logger.With(keyValues...)
+}
+
+func (n *namedLogger) WithFields(fields Fields) ILoggerEx {
+	logger, _ := n.target()
+	return logger.WithFields(fields)
+}
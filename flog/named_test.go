@@ -0,0 +1,143 @@
+package flog
+
+import (
+	"bytes"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestGetLogger(t *testing.T) {
+	tl := useRecordLogger(t)
+	tl.SetLevel(InfoLevel)
+
+	logger := GetLogger("named_test")
+	if GetLogger("named_test").(*namedLogger).state != logger.(*namedLogger).state {
+		t.Errorf("the same name should share the state")
+	}
+	logger.Debugf("filtered by the level of current logger")
+	logger.Infof("shared")
+	records := tl.Records()
+	if len(records) != 1 || !strings.HasSuffix(records[0].File, "named_test.go") ||
+		len(records[0].Fields) != 0 {
+		t.Fatalf("wrong records %+v", records)
+	}
+
+	SetLoggerLevel("named_test", DebugLevel)
+	defer ResetLoggerLevel("named_test")
+	logger.Debugf("debug by named level")
+	Debugf("filtered")
+	if tl.Count(DebugLevel, "") != 1 || logger.GetLevel() != DebugLevel {
+		t.Errorf("wrong records %+v", tl.Records())
+	}
+
+	var buf bytes.Buffer
+	SetLoggerSink("named_test", NewWriterSink(&buf, NewLogfmtEncoder()))
+	logger.Infof("to own sink")
+	logger.Tracef("filtered")
+	SetLoggerSink("named_test", nil)
+	logger.Infof("back")
+	if !strings.Contains(buf.String(), "msg=\"to own sink\" logger=named_test") || strings.Contains(buf.String(), "filtered") {
+		t.Errorf("wrong sink output: %s", buf.String())
+	}
+	if tl.Count(InfoLevel, "to own sink") != 0 || tl.Count(InfoLevel, "back") != 1 {
+		t.Errorf("wrong records %+v", tl.Records())
+	}
+}
+
+// valueLogger is not comparable, the named loggers should not compare it
+type valueLogger struct {
+	*recordLogger
+	tags []string
+}
+
+func TestNameFieldFactory(t *testing.T) {
+	tl := useRecordLogger(t)
+	defer SetNamedLoggerFactory(nil)
+	SetNamedLoggerFactory(NameFieldFactory)
+
+	logger := GetLogger("named_test")
+	logger.Infof("with name")
+	if records := tl.Records(); len(records) != 1 || len(records[0].Fields) != 1 ||
+		records[0].Fields[0].Key != LoggerNameField || records[0].Fields[0].Value != "named_test" {
+		t.Fatalf("wrong records %+v", records)
+	}
+
+	// the named logger follows the current logger, even if it's not comparable
+	other := useRecordLogger(t)
+	SetLogger(valueLogger{recordLogger: other})
+	logger.Infof("to other")
+	logger.Infof("to other again")
+	if other.Count(InfoLevel, "to other") != 2 || tl.Count(InfoLevel, "to other") != 0 {
+		t.Errorf("wrong records %+v", other.Records())
+	}
+}
+
+func TestNamedLoggerFactory(t *testing.T) {
+	defer SetNamedLoggerFactory(nil)
+
+	created := make(map[string]*simpleLogger)
+	logger := GetLogger("ioext_test")
+	SetNamedLoggerFactory(func(name string) ILogger {
+		created[name] = &simpleLogger{}
+		return created[name]
+	})
+	logger.Infof("first")
+	logger.Warnf("second")
+	logger.WarnExWithPosf("verify.go", 1, "Verify", "pos")
+	if len(created) != 1 || created["ioext_test"] == nil {
+		t.Fatalf("the factory should be called once lazily, %v", created)
+	}
+	if lines := strings.Join(created["ioext_test"].lines, ","); lines != "I:first,W:second,W:pos" {
+		t.Errorf("wrong lines %s", lines)
+	}
+
+	SetNamedLoggerFactory(func(name string) ILogger {
+		created[name+"2"] = &simpleLogger{}
+		return created[name+"2"]
+	})
+	logger.Infof("third")
+	if len(created["ioext_test2"].lines) != 1 {
+		t.Errorf("the new factory should be used")
+	}
+}
+
+// callerLogger is the simple ILogger which gets the position of its caller's caller like the old loggers
+type callerLogger struct {
+	simpleLogger
+	positions []string
+}
+
+func (c *callerLogger) record() {
+	// skip record and Debugf/Infof
+	_, file, line, _ := runtime.Caller(3)
+	c.positions = append(c.positions, filepath.Base(file)+":"+strconv.Itoa(line))
+}
+
+func (c *callerLogger) Debugf(format string, args ...any) {
+	c.record()
+}
+
+func (c *callerLogger) Infof(format string, args ...any) {
+	c.record()
+}
+
+func TestNamedLoggerCallDepth(t *testing.T) {
+	oldLogger := CurrentLogger()
+	defer SetLogger(oldLogger)
+	simple := &callerLogger{}
+	SetLoggerFactory(func() ILogger { return simple })
+
+	logger := GetLogger("named_test")
+	_, _, line, _ := runtime.Caller(0)
+	logger.Debugf("debug")
+	logger.Infof("info")
+	Debugf("package debug")
+	expected := []string{"named_test.go:" + strconv.Itoa(line+1), "named_test.go:" + strconv.Itoa(line+2),
+		"named_test.go:" + strconv.Itoa(line+3)}
+	if strings.Join(simple.positions, ",") != strings.Join(expected, ",") {
+		t.Errorf("wrong positions %v, expected %v", simple.positions, expected)
+	}
+}
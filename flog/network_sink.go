@@ -0,0 +1,366 @@
+package flog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NetworkConfig is the config of NetworkSink
+type NetworkConfig struct {
+	// Network is "tcp" or "udp"(also "tcp4", "udp6"...)
+	Network string
+	// Address is the address of the collector, example: "10.0.0.1:5170"
+	Address string
+	// Encoder default is NewJSONEncoder(), the output should be one line
+	Encoder Encoder
+
+	// QueueDir is the folder of the queue files, required. the messages left by the previous process are sent
+	// after connected, so the folder should not be shared by processes.
+	QueueDir string
+	// MaxQueueBytes is the max bytes of the queue files, the oldest messages are dropped when full, default is 64MB
+	MaxQueueBytes int64
+
+	// MinBackoff and MaxBackoff are the range of the exponential backoff between reconnections,
+	// default are 100ms and 30s
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// DialTimeout and WriteTimeout default are 5s
+	DialTimeout  time.Duration
+	WriteTimeout time.Duration
+}
+
+// NetworkSinkStats is the health of NetworkSink
+type NetworkSinkStats struct {
+	Connected  bool   `json:"connected"`
+	QueueDepth int    `json:"queue_depth"` // count of messages in the queue files
+	QueueBytes int64  `json:"queue_bytes"`
+	Sent       uint64 `json:"sent"`
+	Dropped    uint64 `json:"dropped"` // dropped because the queue is full or fail to write the queue file
+	Reconnects uint64 `json:"reconnects"`
+	LastError  string `json:"last_error,omitempty"`
+}
+
+// NetworkSink sends the records as newline-delimited JSON(or other Encoder) to the collector by TCP or UDP,
+// for UDP every message is one packet. example:
+//
+//	sink, err := flog.NewNetworkSink(flog.NetworkConfig{Network: "tcp", Address: "10.0.0.1:5170", QueueDir: "logs/queue"})
+//	flog.EnableTee(flog.TeeEntry{Sink: flog.NewWriterSink(os.Stderr, nil), Level: flog.InfoLevel},
+//		flog.TeeEntry{Sink: sink, Level: flog.WarnLevel})
+//
+// while disconnected the messages are appended to the bounded queue files, and a background goroutine
+// reconnects with exponential backoff, then sends the queued messages in order before the new ones.
+// the message may be sent twice if the connection breaks while sending, or the process restarts.
+type NetworkSink struct {
+	config NetworkConfig
+
+	stream bool
+
+	// drainMu serializes the replay of the background goroutine and Flush
+	drainMu sync.Mutex
+
+	mu         sync.Mutex
+	conn       net.Conn
+	queue      *diskQueue
+	sent       uint64
+	dropped    uint64
+	reconnects uint64
+	lastErr    error
+	closed     bool
+
+	wake    chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// NewNetworkSink opens the queue and starts connecting in background, the first connection failure is not
+// an error, the messages are queued until connected.
+func NewNetworkSink(config NetworkConfig) (*NetworkSink, error) {
+	if !strings.HasPrefix(config.Network, "tcp") && !strings.HasPrefix(config.Network, "udp") {
+		return nil, fmt.Errorf("flog: unsupported network %q, should be tcp or udp", config.Network)
+	}
+	if config.QueueDir == "" {
+		return nil, errors.New("flog: QueueDir of NetworkSink is required")
+	}
+	if config.Encoder == nil {
+		config.Encoder = NewJSONEncoder()
+	}
+	if config.MaxQueueBytes <= 0 {
+		config.MaxQueueBytes = 64 << 20
+	}
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = 100 * time.Millisecond
+	}
+	if config.MaxBackoff < config.MinBackoff {
+		config.MaxBackoff = 30 * time.Second
+		if config.MaxBackoff < config.MinBackoff {
+			config.MaxBackoff = config.MinBackoff
+		}
+	}
+	if config.DialTimeout <= 0 {
+		config.DialTimeout = 5 * time.Second
+	}
+	if config.WriteTimeout <= 0 {
+		config.WriteTimeout = 5 * time.Second
+	}
+	queue, err := openDiskQueue(config.QueueDir, config.MaxQueueBytes)
+	if err != nil {
+		return nil, err
+	}
+	s := &NetworkSink{
+		config:  config,
+		stream:  strings.HasPrefix(config.Network, "tcp"),
+		queue:   queue,
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go s.run()
+	s.notify()
+	return s, nil
+}
+
+func (s *NetworkSink) WriteRecord(r *Record) error {
+	var buf bytes.Buffer
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrSinkClosed
+	}
+	if err := s.config.Encoder.Encode(&buf, r); err != nil {
+		return err
+	}
+	msg := buf.Bytes()
+	if !bytes.HasSuffix(msg, []byte{'\n'}) {
+		msg = append(msg, '\n')
+	}
+
+	// send directly only when nothing is queued, so the order is kept
+	if s.conn != nil && s.queue.count == 0 {
+		if err := s.send(msg); err == nil {
+			return nil
+		}
+	}
+	dropped, err := s.queue.push(msg)
+	s.dropped += uint64(dropped)
+	s.notify()
+	if err != nil {
+		s.lastErr = err
+		return err
+	}
+	return nil
+}
+
+// send writes one message, closes the connection when fail, called with mu
+func (s *NetworkSink) send(msg []byte) error {
+	_ = s.conn.SetWriteDeadline(time.Now().Add(s.config.WriteTimeout))
+	if _, err := s.conn.Write(msg); err != nil {
+		s.disconnect(s.conn, err)
+		return err
+	}
+	s.sent++
+	return nil
+}
+
+// disconnect closes the conn if it's still the current one, called with mu
+func (s *NetworkSink) disconnect(conn net.Conn, err error) {
+	if s.conn != conn {
+		return
+	}
+	_ = conn.Close()
+	s.conn = nil
+	s.lastErr = err
+	s.notify()
+}
+
+func (s *NetworkSink) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run reconnects and sends the queued messages in background
+func (s *NetworkSink) run() {
+	defer close(s.stopped)
+	backoff := s.config.MinBackoff
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-s.wake:
+		}
+		for !s.drain() {
+			timer := time.NewTimer(backoff)
+			select {
+			case <-s.done:
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			if backoff *= 2; backoff > s.config.MaxBackoff {
+				backoff = s.config.MaxBackoff
+			}
+		}
+		backoff = s.config.MinBackoff
+	}
+}
+
+// replayBatchSize is the max count of the queued messages sent without mu
+const replayBatchSize = 256
+
+// drain connects if disconnected and sends the queued messages, returns true when all are sent.
+// the messages are sent without mu in batches, so WriteRecord is not blocked by the replay, the new records are
+// queued after the replaying ones until the queue is empty.
+func (s *NetworkSink) drain() bool {
+	s.drainMu.Lock()
+	defer s.drainMu.Unlock()
+
+	s.mu.Lock()
+	conn, closed := s.conn, s.closed
+	s.mu.Unlock()
+	if closed {
+		return true
+	}
+	if conn == nil {
+		// dial without mu, so WriteRecord is not blocked
+		newConn, err := net.DialTimeout(s.config.Network, s.config.Address, s.config.DialTimeout)
+		s.mu.Lock()
+		switch {
+		case err != nil:
+			s.lastErr = err
+		case s.closed || s.conn != nil:
+			_ = newConn.Close()
+		default:
+			s.conn = newConn
+			s.reconnects++
+			go s.watch(newConn)
+		}
+		conn, closed = s.conn, s.closed
+		s.mu.Unlock()
+		if err != nil {
+			return false
+		}
+	}
+
+	for !closed && conn != nil {
+		s.mu.Lock()
+		batch, first, err := s.queue.peekBatch(replayBatchSize)
+		if err != nil {
+			// the queue file is broken, skip it
+			s.lastErr = err
+			s.dropped += uint64(s.queue.segments[0].count)
+			s.queue.removeHead()
+			s.mu.Unlock()
+			continue
+		}
+		s.mu.Unlock()
+		if len(batch) == 0 {
+			return true
+		}
+
+		sent, err := s.sendBatch(conn, batch)
+		s.mu.Lock()
+		s.sent += uint64(sent)
+		// the messages may be dropped by WriteRecord when the queue is full while sending
+		s.queue.popUntil(first + uint64(sent))
+		if err != nil {
+			s.disconnect(conn, err)
+		}
+		conn, closed = s.conn, s.closed
+		s.mu.Unlock()
+		if err != nil {
+			return false
+		}
+	}
+	return closed
+}
+
+// sendBatch writes the messages without mu, returns the count of the messages written
+func (s *NetworkSink) sendBatch(conn net.Conn, batch [][]byte) (int, error) {
+	_ = conn.SetWriteDeadline(time.Now().Add(s.config.WriteTimeout))
+	if s.stream {
+		// one write for the stream, if it fails the whole batch is sent again
+		buffers := net.Buffers(batch)
+		if _, err := buffers.WriteTo(conn); err != nil {
+			return 0, err
+		}
+		return len(batch), nil
+	}
+	for i, msg := range batch {
+		if _, err := conn.Write(msg); err != nil {
+			return i, err
+		}
+	}
+	return len(batch), nil
+}
+
+// watch reads the connection to find it's closed by the collector, the collector should not send anything
+func (s *NetworkSink) watch(conn net.Conn) {
+	buf := make([]byte, 512)
+	for {
+		if _, err := conn.Read(buf); err != nil {
+			s.mu.Lock()
+			s.disconnect(conn, err)
+			s.mu.Unlock()
+			return
+		}
+	}
+}
+
+// Stats returns the health of the sink
+func (s *NetworkSink) Stats() NetworkSinkStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := NetworkSinkStats{
+		Connected:  s.conn != nil,
+		QueueDepth: s.queue.count,
+		QueueBytes: s.queue.size,
+		Sent:       s.sent,
+		Dropped:    s.dropped,
+		Reconnects: s.reconnects,
+	}
+	if s.lastErr != nil {
+		stats.LastError = s.lastErr.Error()
+	}
+	return stats
+}
+
+// Flush tries to send the queued messages once, returns error if some messages are still queued
+func (s *NetworkSink) Flush() error {
+	if s.drain() {
+		return nil
+	}
+	stats := s.Stats()
+	return fmt.Errorf("flog: %d messages queued, last error: %s", stats.QueueDepth, stats.LastError)
+}
+
+// Close stops the background goroutine and closes the connection, the queued messages are kept in the
+// queue files and sent by the next NetworkSink of the same QueueDir.
+func (s *NetworkSink) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	close(s.done)
+	<-s.stopped
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queue.close()
+	if s.conn != nil {
+		err := s.conn.Close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
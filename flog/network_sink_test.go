@@ -0,0 +1,280 @@
+package flog
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testCollector accepts the connections and sends the "msg" of every line to ch
+type testCollector struct {
+	listener net.Listener
+	ch       chan string
+	// gate blocks the reading until closed, nil means read immediately
+	gate chan struct{}
+
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+func startTestCollector(t *testing.T, address string, ch chan string) *testCollector {
+	return startGatedTestCollector(t, address, ch, nil)
+}
+
+func startGatedTestCollector(t *testing.T, address string, ch chan string, gate chan struct{}) *testCollector {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &testCollector{listener: listener, ch: ch, gate: gate}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			c.mu.Lock()
+			c.conns = append(c.conns, conn)
+			c.mu.Unlock()
+			go c.read(conn)
+		}
+	}()
+	return c
+}
+
+func (c *testCollector) read(conn net.Conn) {
+	if c.gate != nil {
+		<-c.gate
+	}
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		var line struct {
+			Msg string `json:"msg"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &line) == nil {
+			c.ch <- line.Msg
+		}
+	}
+}
+
+// kill closes the listener and all the connections
+func (c *testCollector) kill() {
+	_ = c.listener.Close()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, conn := range c.conns {
+		_ = conn.Close()
+	}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func receiveMessages(t *testing.T, ch chan string, expected ...string) {
+	for _, want := range expected {
+		select {
+		case got := <-ch:
+			if got != want {
+				t.Fatalf("receive %q, want %q", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for %q", want)
+		}
+	}
+}
+
+func newNetworkTestRecord(msg string, fields ...Field) *Record {
+	r := newTestRecord()
+	r.Message, r.Fields = msg, fields
+	return r
+}
+
+func TestNetworkSinkReconnect(t *testing.T) {
+	ch := make(chan string, 100)
+	collector := startTestCollector(t, "127.0.0.1:0", ch)
+	address := collector.listener.Addr().String()
+
+	sink, err := NewNetworkSink(NetworkConfig{
+		Network: "tcp", Address: address, QueueDir: t.TempDir(),
+		MinBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	waitFor(t, "connected", func() bool { return sink.Stats().Connected })
+	_ = sink.WriteRecord(newNetworkTestRecord("m0"))
+	receiveMessages(t, ch, "m0")
+
+	collector.kill()
+	waitFor(t, "disconnected", func() bool { return !sink.Stats().Connected })
+	for i := 1; i <= 5; i++ {
+		if err = sink.WriteRecord(newNetworkTestRecord("m" + strconv.Itoa(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if stats := sink.Stats(); stats.QueueDepth != 5 || stats.QueueBytes == 0 || stats.LastError == "" {
+		t.Errorf("wrong stats while disconnected: %+v", stats)
+	}
+	if sink.Flush() == nil {
+		t.Errorf("flush should fail while disconnected")
+	}
+
+	collector = startTestCollector(t, address, ch)
+	defer collector.kill()
+	receiveMessages(t, ch, "m1", "m2", "m3", "m4", "m5")
+	_ = sink.WriteRecord(newNetworkTestRecord("m6"))
+	receiveMessages(t, ch, "m6")
+
+	stats := sink.Stats()
+	if !stats.Connected || stats.QueueDepth != 0 || stats.QueueBytes != 0 || stats.Sent != 7 || stats.Reconnects != 2 || stats.Dropped != 0 {
+		t.Errorf("wrong stats after reconnected: %+v", stats)
+	}
+	if err = sink.Flush(); err != nil {
+		t.Error(err)
+	}
+	if err = sink.Close(); err != nil || sink.WriteRecord(newNetworkTestRecord("closed")) != ErrSinkClosed {
+		t.Errorf("write after close should fail, close err=%v", err)
+	}
+}
+
+func TestNetworkSinkQueue(t *testing.T) {
+	// a closed port, so the messages are queued
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	address := listener.Addr().String()
+	_ = listener.Close()
+
+	dir := t.TempDir()
+	config := NetworkConfig{Network: "tcp", Address: address, QueueDir: dir, MaxQueueBytes: 8192, MinBackoff: time.Hour}
+	sink, err := NewNetworkSink(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 200; i++ {
+		_ = sink.WriteRecord(newNetworkTestRecord("queued" + strconv.Itoa(i)))
+	}
+	stats := sink.Stats()
+	if stats.Dropped == 0 || stats.QueueBytes > config.MaxQueueBytes || stats.QueueDepth+int(stats.Dropped) != 200 {
+		t.Errorf("wrong stats when queue is full: %+v", stats)
+	}
+	_ = sink.Close()
+
+	// the next sink sends the messages left by the previous one, the oldest are dropped
+	ch := make(chan string, 200)
+	collector := startTestCollector(t, address, ch)
+	defer collector.kill()
+	config.MinBackoff = 10 * time.Millisecond
+	sink, err = NewNetworkSink(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	if depth := sink.Stats().QueueDepth; depth != stats.QueueDepth {
+		t.Errorf("queue depth should be %d after reopen, got %d", stats.QueueDepth, depth)
+	}
+	var expected []string
+	for i := int(stats.Dropped); i < 200; i++ {
+		expected = append(expected, "queued"+strconv.Itoa(i))
+	}
+	receiveMessages(t, ch, expected...)
+	waitFor(t, "queue empty", func() bool { return sink.Stats().QueueDepth == 0 })
+}
+
+func TestNetworkSinkWriteWhileReplaying(t *testing.T) {
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	address := listener.Addr().String()
+	_ = listener.Close()
+
+	config := NetworkConfig{Network: "tcp", Address: address, QueueDir: t.TempDir(), MinBackoff: time.Hour,
+		WriteTimeout: 10 * time.Second}
+	sink, err := NewNetworkSink(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the backlog is much larger than the socket buffers, so the replay blocks until the collector reads
+	const backlog = 4000
+	padding := Field{Key: "pad", Value: strings.Repeat("x", 8192)}
+	for i := 0; i < backlog; i++ {
+		_ = sink.WriteRecord(newNetworkTestRecord("b"+strconv.Itoa(i), padding))
+	}
+	_ = sink.Close()
+
+	ch := make(chan string, backlog+10)
+	gate := make(chan struct{})
+	collector := startGatedTestCollector(t, address, ch, gate)
+	defer collector.kill()
+	config.MinBackoff = 10 * time.Millisecond
+	sink, err = NewNetworkSink(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	waitFor(t, "connected", func() bool { return sink.Stats().Connected })
+	time.Sleep(50 * time.Millisecond)
+
+	start := time.Now()
+	_ = sink.WriteRecord(newNetworkTestRecord("new"))
+	if cost := time.Since(start); cost > time.Second {
+		t.Errorf("WriteRecord is blocked by the replay for %v", cost)
+	}
+	if depth := sink.Stats().QueueDepth; depth == 0 {
+		t.Errorf("the backlog should be replaying")
+	}
+
+	close(gate)
+	var expected []string
+	for i := 0; i < backlog; i++ {
+		expected = append(expected, "b"+strconv.Itoa(i))
+	}
+	receiveMessages(t, ch, append(expected, "new")...)
+}
+
+func TestNetworkSinkUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	sink, err := NewNetworkSink(NetworkConfig{Network: "udp", Address: conn.LocalAddr().String(), QueueDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	waitFor(t, "connected", func() bool { return sink.Stats().Connected })
+	_ = sink.WriteRecord(newNetworkTestRecord("udp message"))
+
+	buf := make([]byte, 4096)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	var line struct {
+		Msg string `json:"msg"`
+	}
+	if err != nil || json.Unmarshal(buf[:n], &line) != nil || line.Msg != "udp message" {
+		t.Errorf("wrong packet %q, err=%v", buf[:n], err)
+	}
+}
+
+func TestNetworkSinkConfig(t *testing.T) {
+	for _, config := range []NetworkConfig{
+		{Network: "unix", Address: "/tmp/x", QueueDir: t.TempDir()},
+		{Network: "tcp", Address: "127.0.0.1:1"},
+	} {
+		if _, err := NewNetworkSink(config); err == nil {
+			t.Errorf("config %+v should be invalid", config)
+		}
+	}
+}
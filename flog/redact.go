@@ -0,0 +1,164 @@
+package flog
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// RedactedMask replaces the secret in the log
+const RedactedMask = "[REDACTED]"
+
+// Redactor is implemented by the value which contains secret(example: token, signature),
+// the printf argument or field value is replaced by the result of Redact before output.
+type Redactor interface {
+	Redact() string
+}
+
+// the builtin patterns for AddRedactPattern, they are not enabled by default
+var (
+	// RedactBearerToken matches "Bearer xxx" in the Authorization header
+	RedactBearerToken = regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]+=*`)
+	// RedactAWSAccessKey matches the AWS access key id
+	RedactAWSAccessKey = regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)
+	// RedactEmail matches the email address
+	RedactEmail = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+)
+
+type redactConfig struct {
+	keys     map[string]bool // lower case
+	patterns []*regexp.Regexp
+}
+
+var (
+	_redactMu sync.Mutex
+	_redact   atomic.Value // *redactConfig, nil when there is no key and pattern
+)
+
+func init() {
+	_redact.Store((*redactConfig)(nil))
+}
+
+// RegisterRedactKeys registers the field keys(case-insensitive) whose values are always masked, example:
+//
+//	flog.RegisterRedactKeys("token", "signature", "password")
+func RegisterRedactKeys(keys ...string) {
+	updateRedactConfig(func(config *redactConfig) {
+		for _, key := range keys {
+			config.keys[strings.ToLower(key)] = true
+		}
+	})
+}
+
+// AddRedactPattern adds the pattern, the matched text in message and field values is masked, example:
+//
+//	flog.AddRedactPattern(flog.RedactBearerToken)
+func AddRedactPattern(patterns ...*regexp.Regexp) {
+	updateRedactConfig(func(config *redactConfig) {
+		config.patterns = append(config.patterns, patterns...)
+	})
+}
+
+// ResetRedaction removes all the registered keys and patterns, the Redactor values are still redacted
+func ResetRedaction() {
+	_redactMu.Lock()
+	defer _redactMu.Unlock()
+	_redact.Store((*redactConfig)(nil))
+}
+
+// updateRedactConfig copies the config and updates it
+func updateRedactConfig(update func(config *redactConfig)) {
+	_redactMu.Lock()
+	defer _redactMu.Unlock()
+
+	config := &redactConfig{keys: make(map[string]bool)}
+	if old := loadRedactConfig(); old != nil {
+		for key := range old.keys {
+			config.keys[key] = true
+		}
+		config.patterns = append(config.patterns, old.patterns...)
+	}
+	update(config)
+	_redact.Store(config)
+}
+
+func loadRedactConfig() *redactConfig {
+	return _redact.Load().(*redactConfig)
+}
+
+// resolveArgs evaluates the Lazy arguments and replaces the Redactor arguments, returns args itself if nothing changed
+func resolveArgs(args []any) []any {
+	var result []any
+	for i, arg := range args {
+		if lazy, ok := arg.(lazyValue); ok {
+			arg = lazy()
+		} else if _, ok = arg.(Redactor); !ok {
+			continue
+		}
+		if r, ok := arg.(Redactor); ok {
+			arg = r.Redact()
+		}
+		if result == nil {
+			result = append([]any(nil), args...)
+		}
+		result[i] = arg
+	}
+	if result == nil {
+		return args
+	}
+	return result
+}
+
+// redactMessage masks the text matched by the patterns
+func redactMessage(msg string) string {
+	config := loadRedactConfig()
+	if config == nil {
+		return msg
+	}
+	return config.redactString(msg)
+}
+
+func (config *redactConfig) redactString(s string) string {
+	for _, pattern := range config.patterns {
+		s = pattern.ReplaceAllString(s, RedactedMask)
+	}
+	return s
+}
+
+// resolveFields evaluates the Lazy values, masks the values of registered keys, Redactor values and the text
+// matched by the patterns, returns fields itself if nothing changed
+func resolveFields(fields []Field) []Field {
+	config := loadRedactConfig()
+	var result []Field
+	for i, f := range fields {
+		value, changed := f.Value, false
+		if lazy, ok := value.(lazyValue); ok {
+			value, changed = lazy(), true
+		}
+		if r, ok := value.(Redactor); ok {
+			value, changed = r.Redact(), true
+		}
+		if config != nil {
+			if config.keys[strings.ToLower(f.Key)] {
+				value, changed = RedactedMask, true
+			} else if len(config.patterns) > 0 {
+				if s := fieldValueString(value); s != "" {
+					if redacted := config.redactString(s); redacted != s {
+						value, changed = redacted, true
+					}
+				}
+			}
+		}
+		if changed {
+			if result == nil {
+				result = append([]Field(nil), fields...)
+			}
+			result[i].Value = value
+		}
+	}
+	if result == nil {
+		return fields
+	}
+	return result
+}
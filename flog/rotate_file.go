@@ -0,0 +1,341 @@
+package flog
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	backupTimeFormat = "20060102T150405.000"
+	compressSuffix   = ".gz"
+
+	// rotateRetryDelay is the delay to retry after the rotation fails in Write, so it's not retried by every Write
+	rotateRetryDelay = time.Second
+)
+
+// renameFile is replaced in unit test to simulate the rename failure
+var renameFile = os.Rename
+
+// RotateConfig is the config of RotateFile
+type RotateConfig struct {
+	// FileName is the current log file, backups are in the same folder, example: app.log => app-20230506T070809.000.log
+	FileName string
+
+	// MaxSize is the max bytes of the current file before rotate, 0 means no size rotate
+	MaxSize int64
+
+	// Interval is the time rotate interval, aligned to local midnight(example: time.Hour or 24*time.Hour),
+	// 0 means no time rotate
+	Interval time.Duration
+
+	// MaxBackups is the max count of backups to keep, 0 means keep all
+	MaxBackups int
+
+	// MaxAge is the max age of the backups(by the time in file name), 0 means no delete by age
+	MaxAge time.Duration
+
+	// Compress the backups with gzip
+	Compress bool
+
+	// OnRotateError is called when the rotation fails in Write(not locked, so it can log to the RotateFile),
+	// the data is still written to the current file, and the rotation is retried later. can be nil.
+	OnRotateError func(err error)
+}
+
+// RotateFile is an io.WriteCloser which rotates the file by size and/or by interval,
+// used as the output of the logger, example:
+//
+//	rf, err := flog.NewRotateFile(flog.RotateConfig{FileName: "logs/app.log", MaxSize: 100 << 20, MaxBackups: 10})
+//	flog.SetOutput(rf)
+//
+// it's safe for concurrent use, every Write is written into one file(never split when rotate).
+type RotateFile struct {
+	config RotateConfig
+
+	mu          sync.Mutex
+	file        *os.File // nil only when reopen fails after rotate, then Write retries to open it
+	size        int64
+	nextRotate  time.Time
+	retryRotate time.Time // no rotation in Write before it after the rotation fails
+	closed      bool
+
+	// compress and clean backups in background, so Write will not be blocked
+	millCh   chan struct{}
+	millDone chan struct{}
+
+	// now is replaced in unit test, only used with mu
+	now func() time.Time
+}
+
+// NewRotateFile opens(or create) the log file for append
+func NewRotateFile(config RotateConfig) (*RotateFile, error) {
+	if config.FileName == "" {
+		return nil, errors.New("flog: empty rotate file name")
+	}
+	rf := &RotateFile{
+		config:   config,
+		millCh:   make(chan struct{}, 1),
+		millDone: make(chan struct{}),
+		now:      time.Now,
+	}
+	if err := rf.openFile(); err != nil {
+		return nil, err
+	}
+	go rf.millRoutine()
+	return rf, nil
+}
+
+func (rf *RotateFile) openFile() error {
+	if err := os.MkdirAll(filepath.Dir(rf.config.FileName), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(rf.config.FileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	rf.file = file
+	rf.size = stat.Size()
+	if rf.config.Interval > 0 {
+		rf.nextRotate = nextRotateTime(rf.now(), rf.config.Interval)
+	}
+	return nil
+}
+
+// nextRotateTime returns the next boundary after now, aligned to local midnight
+func nextRotateTime(now time.Time, interval time.Duration) time.Time {
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	count := now.Sub(start)/interval + 1
+	return start.Add(count * interval)
+}
+
+// Write writes p into the current file, the failed rotation doesn't lose p, see RotateConfig.OnRotateError
+func (rf *RotateFile) Write(p []byte) (int, error) {
+	n, rotateErr, err := rf.write(p)
+	if rotateErr != nil && rf.config.OnRotateError != nil {
+		rf.config.OnRotateError(rotateErr)
+	}
+	return n, err
+}
+
+func (rf *RotateFile) write(p []byte) (n int, rotateErr error, err error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.closed {
+		return 0, nil, os.ErrClosed
+	}
+	now := rf.now()
+	needRotate := rf.config.MaxSize > 0 && rf.size > 0 && rf.size+int64(len(p)) > rf.config.MaxSize
+	if !needRotate && rf.config.Interval > 0 && !now.Before(rf.nextRotate) {
+		needRotate = true
+	}
+	if needRotate && !now.Before(rf.retryRotate) {
+		if rotateErr = rf.rotate(); rotateErr != nil {
+			rf.retryRotate = now.Add(rotateRetryDelay)
+		}
+	}
+	if rf.file == nil {
+		if err = rf.openFile(); err != nil {
+			return 0, rotateErr, err
+		}
+	}
+	n, err = rf.file.Write(p)
+	rf.size += int64(n)
+	return n, rotateErr, err
+}
+
+// Rotate closes the current file and rename it to backup, then open a new one,
+// it can be used when receive SIGHUP
+func (rf *RotateFile) Rotate() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.closed {
+		return os.ErrClosed
+	}
+	return rf.rotate()
+}
+
+// rotate renames the current file to backup and opens a new one, the current file is reopened if the rename fails,
+// so the later logs are not lost. rf.file is nil when the open fails.
+func (rf *RotateFile) rotate() error {
+	var err error
+	if rf.file != nil {
+		// the file can not be used after Close even if it fails, so go on
+		err = rf.file.Close()
+		rf.file = nil
+	}
+	backupName := rf.backupName(rf.now())
+	if renameErr := renameFile(rf.config.FileName, backupName); renameErr != nil && !os.IsNotExist(renameErr) {
+		if err == nil {
+			err = renameErr
+		}
+	} else {
+		select {
+		case rf.millCh <- struct{}{}:
+		default:
+			// already has pending mill request
+		}
+	}
+	if openErr := rf.openFile(); openErr != nil {
+		return openErr
+	}
+	return err
+}
+
+func (rf *RotateFile) splitFileName() (prefix string, ext string) {
+	ext = filepath.Ext(rf.config.FileName)
+	return strings.TrimSuffix(rf.config.FileName, ext) + "-", ext
+}
+
+// backupName returns the unused backup name, add 1ms until not exist when rotate quickly
+func (rf *RotateFile) backupName(t time.Time) string {
+	prefix, ext := rf.splitFileName()
+	for {
+		name := prefix + t.Format(backupTimeFormat) + ext
+		if !fileExists(name) && !fileExists(name+compressSuffix) {
+			return name
+		}
+		t = t.Add(time.Millisecond)
+	}
+}
+
+func fileExists(name string) bool {
+	_, err := os.Lstat(name)
+	return err == nil
+}
+
+type backupInfo struct {
+	path string
+	t    time.Time
+}
+
+// listBackups returns the backups sorted by time, newest first
+func (rf *RotateFile) listBackups() ([]backupInfo, error) {
+	prefix, ext := rf.splitFileName()
+	entries, err := os.ReadDir(filepath.Dir(rf.config.FileName))
+	if err != nil {
+		return nil, err
+	}
+	basePrefix := filepath.Base(prefix)
+	var backups []backupInfo
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, basePrefix) {
+			continue
+		}
+		timeText := strings.TrimSuffix(strings.TrimSuffix(name[len(basePrefix):], compressSuffix), ext)
+		t, err := time.ParseInLocation(backupTimeFormat, timeText, time.Local)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backupInfo{path: filepath.Join(filepath.Dir(prefix), name), t: t})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].t.After(backups[j].t)
+	})
+	return backups, nil
+}
+
+func (rf *RotateFile) millRoutine() {
+	defer close(rf.millDone)
+	for range rf.millCh {
+		// errors can not be logged here(may log to self), just ignore and retry at next rotate
+		_ = rf.millOnce()
+	}
+}
+
+// millOnce compresses the uncompressed backups, then removes the backups exceed MaxBackups or MaxAge
+func (rf *RotateFile) millOnce() error {
+	backups, err := rf.listBackups()
+	if err != nil {
+		return err
+	}
+	var remains []backupInfo
+	// just return first error
+	keepFirst := func(e error) {
+		if err == nil && e != nil {
+			err = e
+		}
+	}
+	cutoff := time.Now().Add(-rf.config.MaxAge)
+	for i, backup := range backups {
+		if (rf.config.MaxBackups > 0 && i >= rf.config.MaxBackups) ||
+			(rf.config.MaxAge > 0 && backup.t.Before(cutoff)) {
+			keepFirst(os.Remove(backup.path))
+			continue
+		}
+		remains = append(remains, backup)
+	}
+	if rf.config.Compress {
+		for _, backup := range remains {
+			if !strings.HasSuffix(backup.path, compressSuffix) {
+				keepFirst(compressFile(backup.path, backup.path+compressSuffix))
+			}
+		}
+	}
+	return err
+}
+
+// compressFile gzip the src to dst, then remove the src
+func compressFile(src, dst string) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := dst + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(out)
+	_, err = io.Copy(gz, in)
+	if closeErr := gz.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, dst)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("compress %s fail: %w", src, err)
+	}
+	_ = in.Close()
+	return os.Remove(src)
+}
+
+// Close closes the current file, and waits the background compress and clean finished
+func (rf *RotateFile) Close() error {
+	rf.mu.Lock()
+	if rf.closed {
+		rf.mu.Unlock()
+		return nil
+	}
+	rf.closed = true
+	var err error
+	if rf.file != nil {
+		err = rf.file.Close()
+	}
+	close(rf.millCh)
+	rf.mu.Unlock()
+
+	<-rf.millDone
+	return err
+}
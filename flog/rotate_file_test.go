@@ -0,0 +1,162 @@
+package flog
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countLines returns the line count of all the log files(include gzip backups) in folder
+func countLines(t *testing.T, folder string) (int, int) {
+	entries, err := os.ReadDir(folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := 0
+	for _, entry := range entries {
+		file, err := os.Open(filepath.Join(folder, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var reader io.Reader = file
+		if strings.HasSuffix(entry.Name(), compressSuffix) {
+			if reader, err = gzip.NewReader(file); err != nil {
+				t.Fatal(err)
+			}
+		}
+		scanner := bufio.NewScanner(reader)
+		for scanner.Scan() {
+			lines++
+		}
+		_ = file.Close()
+	}
+	return lines, len(entries)
+}
+
+func TestRotateFileBySize(t *testing.T) {
+	folder := t.TempDir()
+	rf, err := NewRotateFile(RotateConfig{
+		FileName: filepath.Join(folder, "app.log"),
+		MaxSize:  1024,
+		Compress: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const goroutines, count = 8, 200
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			for j := 0; j < count; j++ {
+				_, _ = fmt.Fprintf(rf, "goroutine %d write line %d\n", index, j)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if err = rf.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines, files := countLines(t, folder)
+	if lines != goroutines*count || files < 2 {
+		t.Errorf("lost lines when rotate, lines=%d, files=%d", lines, files)
+	}
+	backups, _ := rf.listBackups()
+	for _, backup := range backups {
+		if !strings.HasSuffix(backup.path, compressSuffix) {
+			t.Errorf("backup not compressed: %s", backup.path)
+		}
+	}
+}
+
+func TestRotateFileByTimeAndMaxBackups(t *testing.T) {
+	folder := t.TempDir()
+	rf, err := NewRotateFile(RotateConfig{
+		FileName:   filepath.Join(folder, "app.log"),
+		Interval:   time.Hour,
+		MaxBackups: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2023, 5, 6, 7, 8, 9, 0, time.Local)
+	rf.mu.Lock()
+	rf.now = func() time.Time { return now }
+	rf.nextRotate = nextRotateTime(now, time.Hour)
+	rf.mu.Unlock()
+
+	if rf.nextRotate != time.Date(2023, 5, 6, 8, 0, 0, 0, time.Local) {
+		t.Errorf("wrong next rotate time %s", rf.nextRotate)
+	}
+	for i := 0; i < 5; i++ {
+		_, _ = fmt.Fprintf(rf, "hour %d\n", i)
+		now = now.Add(time.Hour)
+	}
+	_ = rf.Close()
+
+	backups, _ := rf.listBackups()
+	if len(backups) != 2 {
+		t.Fatalf("should keep 2 backups, backups=%v", backups)
+	}
+	if !backups[0].t.Equal(time.Date(2023, 5, 6, 11, 8, 9, 0, time.Local)) {
+		t.Errorf("wrong newest backup %s", backups[0].path)
+	}
+	data, _ := os.ReadFile(rf.config.FileName)
+	if string(data) != "hour 4\n" {
+		t.Errorf("wrong current file content %q", data)
+	}
+}
+
+func TestRotateFileRenameFail(t *testing.T) {
+	folder := t.TempDir()
+	var rotateErrs []error
+	rf, err := NewRotateFile(RotateConfig{
+		FileName:      filepath.Join(folder, "app.log"),
+		MaxSize:       10,
+		OnRotateError: func(err error) { rotateErrs = append(rotateErrs, err) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rf.Close()
+	now := time.Date(2023, 5, 6, 7, 8, 9, 0, time.Local)
+	rf.mu.Lock()
+	rf.now = func() time.Time { return now }
+	rf.mu.Unlock()
+
+	renameErr := errors.New("rename fail")
+	defer func() { renameFile = os.Rename }()
+	renameFile = func(string, string) error { return renameErr }
+
+	// the lines are written into the current file when the rotation fails, and it's not retried in rotateRetryDelay
+	for i := 0; i < 3; i++ {
+		if n, err := fmt.Fprintf(rf, "line %d\n", i); n != 7 || err != nil {
+			t.Fatalf("write line %d: n=%d, err=%v", i, n, err)
+		}
+	}
+	if len(rotateErrs) != 1 || rotateErrs[0] != renameErr {
+		t.Errorf("wrong rotate errors %v", rotateErrs)
+	}
+	if data, _ := os.ReadFile(rf.config.FileName); string(data) != "line 0\nline 1\nline 2\n" {
+		t.Errorf("wrong current file content %q", data)
+	}
+
+	// retry after the delay
+	renameFile = os.Rename
+	now = now.Add(rotateRetryDelay)
+	_, _ = fmt.Fprintf(rf, "line %d\n", 3)
+	if lines, files := countLines(t, folder); lines != 4 || files != 2 || len(rotateErrs) != 1 {
+		t.Errorf("should rotate after the delay, lines=%d, files=%d, errors=%v", lines, files, rotateErrs)
+	}
+}
@@ -0,0 +1,271 @@
+package flog
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// SamplingConfig limits the logs of every callsite(file:line), Fatal and Panic are never limited.
+//
+// example: at most 10 logs per second for every callsite, and then every 100th:
+//
+//	flog.SetSampling(&flog.SamplingConfig{Window: time.Second, First: 10, Thereafter: 100})
+//
+// when the window closes, a summary line "suppressed N similar messages" is output at the callsite
+// (by the window timer, at the next log of the callsite, or when flog.Flush is called).
+type SamplingConfig struct {
+	// Window is the period to count First/Thereafter and output the summary, default is 1 second
+	Window time.Duration
+
+	// First is the count of logs output in every window, 0 means no sampling by count
+	First int
+	// Thereafter is the interval after First logs, example: 100 means every 100th log, 0 means drop all
+	Thereafter int
+
+	// Rate is the tokens added per second of the token bucket, 0 means no rate limit
+	Rate float64
+	// Burst is the max tokens of the token bucket, default is 1
+	Burst int
+}
+
+type siteKey struct {
+	file string
+	line int
+}
+
+// siteState is the sampling state of one callsite
+type siteState struct {
+	mu          sync.Mutex
+	level       Level
+	funName     string
+	windowStart time.Time
+	count       int64
+	suppressed  int64
+	tokens      float64
+	lastRefill  time.Time
+}
+
+// sampler is shared by the logger and its child loggers
+type sampler struct {
+	config SamplingConfig
+	sites  sync.Map // siteKey => *siteState
+
+	// owner outputs the summaries when the window closes, nil means only output at the next log of the callsite
+	owner   *defaultLogger
+	timerMu sync.Mutex
+	timer   *time.Timer // armed while some callsite has suppressed logs
+}
+
+func newSampler(config *SamplingConfig) *sampler {
+	if config == nil {
+		return nil
+	}
+	s := &sampler{config: *config}
+	if s.config.Window <= 0 {
+		s.config.Window = time.Second
+	}
+	if s.config.Burst <= 0 {
+		s.config.Burst = 1
+	}
+	return s
+}
+
+// newLoggerSampler returns the sampler which outputs the summaries by owner when the window closes
+func newLoggerSampler(config *SamplingConfig, owner *defaultLogger) *sampler {
+	s := newSampler(config)
+	if s != nil {
+		s.owner = owner
+	}
+	return s
+}
+
+// check returns whether the log is allowed, and the suppressed count of the last window which should be
+// output as summary before the log
+func (s *sampler) check(level Level, fileName string, lineNo int, funName string, now time.Time) (bool, int64) {
+	key := siteKey{file: fileName, line: lineNo}
+	value, ok := s.sites.Load(key)
+	if !ok {
+		value, _ = s.sites.LoadOrStore(key, &siteState{
+			level:       level,
+			funName:     funName,
+			windowStart: now,
+			tokens:      float64(s.config.Burst),
+			lastRefill:  now,
+		})
+	}
+	site := value.(*siteState)
+
+	site.mu.Lock()
+	defer site.mu.Unlock()
+
+	var summary int64
+	if now.Sub(site.windowStart) >= s.config.Window {
+		summary = site.suppressed
+		site.windowStart, site.count, site.suppressed = now, 0, 0
+	}
+	site.count++
+
+	allowed := true
+	if s.config.First > 0 && site.count > int64(s.config.First) {
+		allowed = s.config.Thereafter > 0 && (site.count-int64(s.config.First))%int64(s.config.Thereafter) == 0
+	}
+	if allowed && s.config.Rate > 0 {
+		site.tokens += now.Sub(site.lastRefill).Seconds() * s.config.Rate
+		if site.tokens > float64(s.config.Burst) {
+			site.tokens = float64(s.config.Burst)
+		}
+		site.lastRefill = now
+		if site.tokens >= 1 {
+			site.tokens--
+		} else {
+			allowed = false
+		}
+	}
+	if !allowed {
+		site.suppressed++
+		if site.suppressed == 1 {
+			s.schedule(site.windowStart.Add(s.config.Window).Sub(now))
+		}
+	}
+	return allowed, summary
+}
+
+// schedule arms the timer to output the summaries after d, and registers the sampler to the core of owner
+// until the timer finds nothing left, so the Flush of the owner's core reaches it
+func (s *sampler) schedule(d time.Duration) {
+	if s.owner == nil {
+		return
+	}
+	s.timerMu.Lock()
+	defer s.timerMu.Unlock()
+	if s.timer == nil {
+		s.owner.core.pendingSamplers.Store(s, struct{}{})
+		s.timer = time.AfterFunc(d, s.expire)
+	}
+}
+
+// expire outputs the summaries of the closed windows, and re-arms the timer for the open ones
+func (s *sampler) expire() {
+	s.timerMu.Lock()
+	s.timer = nil
+	s.timerMu.Unlock()
+
+	now := time.Now()
+	var next time.Duration
+	s.sites.Range(func(k, v any) bool {
+		site := v.(*siteState)
+		site.mu.Lock()
+		suppressed := site.suppressed
+		remain := site.windowStart.Add(s.config.Window).Sub(now)
+		if suppressed > 0 && remain <= 0 {
+			// the window is kept, so the next log of the callsite starts a new one without summary
+			site.suppressed = 0
+		}
+		site.mu.Unlock()
+		if suppressed > 0 {
+			if remain <= 0 {
+				s.owner.writeSummary(site.level, k.(siteKey).file, k.(siteKey).line, site.funName, suppressed)
+			} else if next == 0 || remain < next {
+				next = remain
+			}
+		}
+		return true
+	})
+	if next > 0 {
+		s.schedule(next)
+		return
+	}
+	s.timerMu.Lock()
+	if s.timer == nil {
+		s.owner.core.pendingSamplers.Delete(s)
+	}
+	s.timerMu.Unlock()
+}
+
+// takeSummaries returns and resets the suppressed counts of all callsites, called when flush
+func (s *sampler) takeSummaries(fn func(key siteKey, site *siteState, suppressed int64)) {
+	s.sites.Range(func(k, v any) bool {
+		site := v.(*siteState)
+		site.mu.Lock()
+		suppressed := site.suppressed
+		site.suppressed = 0
+		site.mu.Unlock()
+		if suppressed > 0 {
+			fn(k.(siteKey), site, suppressed)
+		}
+		return true
+	})
+}
+
+// SamplingLogger is implemented by the builtin loggers which support sampling
+type SamplingLogger interface {
+	// WithSampling returns a child logger which uses its own sampling config, nil config disables sampling
+	WithSampling(config *SamplingConfig) ILoggerEx
+}
+
+// SetSampling sets the global sampling config of the default logger and its child loggers,
+// nil config disables sampling.
+func SetSampling(config *SamplingConfig) {
+	_defaultLogger.core.sampler.Store(samplerHolder{newLoggerSampler(config, &defaultLogger{core: _defaultLogger.core})})
+}
+
+// WithSampling returns a child of the current logger which uses its own sampling config,
+// the logger created by SetLoggerFactory is returned directly if it's not a SamplingLogger.
+func WithSampling(config *SamplingConfig) ILoggerEx {
+	l := curLogger()
+	if sl, ok := l.(SamplingLogger); ok {
+		return sl.WithSampling(config)
+	}
+	return l
+}
+
+// samplerHolder make sure the atomic.Value always store the same type
+type samplerHolder struct {
+	*sampler
+}
+
+var _onceSites sync.Map // pc => struct{}
+
+// logOncef outputs the log only once for the callsite, which is called by XxxOncef, so skip is 3
+func logOncef(level Level, format string, args ...any) {
+	l := curLogger()
+	if !isEnabledAt(l.GetLevel(), level, 3) {
+		return
+	}
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:])
+	if _, loaded := _onceSites.LoadOrStore(pcs[0], struct{}{}); loaded {
+		return
+	}
+	fileName, lineNo, funName := GetCallStackInfo(3)
+	outputWithPosf(l, level, fileName, lineNo, funName, format, args...)
+}
+
+// DebugOncef outputs the log only once for the callsite
+func DebugOncef(format string, args ...any) {
+	logOncef(DebugLevel, format, args...)
+}
+
+// InfoOncef outputs the log only once for the callsite
+func InfoOncef(format string, args ...any) {
+	logOncef(InfoLevel, format, args...)
+}
+
+// WarnOncef outputs the log only once for the callsite
+func WarnOncef(format string, args ...any) {
+	logOncef(WarnLevel, format, args...)
+}
+
+// ErrorOncef outputs the log only once for the callsite
+func ErrorOncef(format string, args ...any) {
+	logOncef(ErrorLevel, format, args...)
+}
+
+// ResetOnce clears the callsites of XxxOncef, so they can output again
+func ResetOnce() {
+	_onceSites.Range(func(key, _ any) bool {
+		_onceSites.Delete(key)
+		return true
+	})
+}
@@ -0,0 +1,175 @@
+package flog
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lockedBuffer is written by the sampling timer and read by the test
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestSamplerFirstThereafter(t *testing.T) {
+	s := newSampler(&SamplingConfig{Window: time.Minute, First: 3, Thereafter: 5})
+	now := time.Now()
+	allowedCount := 0
+	for i := 0; i < 20; i++ {
+		if allowed, _ := s.check(WarnLevel, "a.go", 1, "f", now); allowed {
+			allowedCount++
+		}
+	}
+	// 1,2,3 and 8,13,18
+	if allowedCount != 6 {
+		t.Errorf("wrong allowed count %d", allowedCount)
+	}
+	allowed, summary := s.check(WarnLevel, "a.go", 1, "f", now.Add(time.Minute))
+	if !allowed || summary != 14 {
+		t.Errorf("wrong next window, allowed=%v, summary=%d", allowed, summary)
+	}
+	if allowed, _ := s.check(WarnLevel, "b.go", 1, "f", now); !allowed {
+		t.Errorf("other callsite should be allowed")
+	}
+}
+
+func TestSamplerRate(t *testing.T) {
+	s := newSampler(&SamplingConfig{Rate: 2, Burst: 2})
+	now := time.Now()
+	var results []bool
+	for _, d := range []time.Duration{0, 0, 0, 400 * time.Millisecond, 500 * time.Millisecond, 500 * time.Millisecond} {
+		allowed, _ := s.check(InfoLevel, "a.go", 1, "f", now.Add(d))
+		results = append(results, allowed)
+	}
+	expected := []bool{true, true, false, false, true, false}
+	for i := range expected {
+		if results[i] != expected[i] {
+			t.Errorf("wrong result %v, expected %v", results, expected)
+			break
+		}
+	}
+}
+
+func TestSampling(t *testing.T) {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	defer SetOutput(stdLogWriter{})
+	SetSampling(&SamplingConfig{Window: time.Hour, First: 2})
+	defer SetSampling(nil)
+
+	for i := 0; i < 10; i++ {
+		WarnExWithPosf("verify.go", 100, "Verify", "file disappeared")
+	}
+	_ = Flush()
+	output := buf.String()
+	if count := strings.Count(output, "file disappeared"); count != 2 {
+		t.Errorf("wrong output count %d: %s", count, output)
+	}
+	if !strings.Contains(output, "verify.go:100 ]") || !strings.Contains(output, "suppressed 8 similar messages") {
+		t.Errorf("wrong summary: %s", output)
+	}
+
+	// flush again should not output the summary
+	buf.Reset()
+	_ = Flush()
+	if buf.Len() != 0 {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
+}
+
+func TestWithSampling(t *testing.T) {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	defer SetOutput(stdLogWriter{})
+
+	l := WithSampling(&SamplingConfig{Window: time.Hour, First: 1}).With("k", "v")
+	for i := 0; i < 3; i++ {
+		l.Warnf("sampled")
+		Warnf("not sampled")
+	}
+	if count := strings.Count(buf.String(), "sampled"); count != 4 {
+		t.Errorf("wrong output count %d: %s", count, buf.String())
+	}
+	if !strings.Contains(buf.String(), "[k=v] sampled") {
+		t.Errorf("child logger should keep fields: %s", buf.String())
+	}
+}
+
+func TestSamplingWindowTimer(t *testing.T) {
+	buf := &lockedBuffer{}
+	SetOutput(buf)
+	defer SetOutput(stdLogWriter{})
+	SetSampling(&SamplingConfig{Window: 50 * time.Millisecond, First: 1})
+	defer SetSampling(nil)
+
+	// a burst followed by silence, the summary is output when the window closes
+	for i := 0; i < 5; i++ {
+		WarnExWithPosf("verify.go", 100, "Verify", "burst")
+	}
+	waitFor(t, "summary", func() bool { return strings.Contains(buf.String(), "suppressed 4 similar messages") })
+	time.Sleep(100 * time.Millisecond)
+	if output := buf.String(); strings.Count(output, "burst") != 1 || strings.Count(output, "suppressed") != 1 {
+		t.Errorf("wrong output: %s", output)
+	}
+}
+
+func TestFlushWithSampling(t *testing.T) {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	defer SetOutput(stdLogWriter{})
+	_ = Flush()
+	buf.Reset()
+
+	// flush of the root logger reaches the sampler of the child logger
+	l := WithSampling(&SamplingConfig{Window: time.Hour, First: 1})
+	for i := 0; i < 3; i++ {
+		l.Warnf("child")
+	}
+	_ = Flush()
+	if strings.Count(buf.String(), "suppressed 2 similar messages") != 1 {
+		t.Errorf("wrong summary: %s", buf.String())
+	}
+}
+
+func TestOncef(t *testing.T) {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	defer SetOutput(stdLogWriter{})
+	defer ResetOnce()
+
+	for i := 0; i < 3; i++ {
+		WarnOncef("once %d", i)
+		InfoOncef("info once")
+	}
+	output := buf.String()
+	if strings.Count(output, "once 0") != 1 || strings.Contains(output, "once 1") || strings.Count(output, "info once") != 1 {
+		t.Errorf("wrong output: %s", output)
+	}
+	if !strings.Contains(output, "sampling_test.go:") {
+		t.Errorf("wrong position: %s", output)
+	}
+
+	ResetOnce()
+	buf.Reset()
+	for i := 0; i < 2; i++ {
+		ErrorOncef("again")
+	}
+	if strings.Count(buf.String(), "again") != 1 {
+		t.Errorf("wrong output after reset: %s", buf.String())
+	}
+}
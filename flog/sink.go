@@ -0,0 +1,117 @@
+package flog
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+)
+
+// Sink receives the records from the logger, and outputs them to somewhere(stderr, file, network...),
+// it must be safe for concurrent use.
+type Sink interface {
+	WriteRecord(r *Record) error
+}
+
+// Flusher is implemented by the Sink(or logger, output) which buffers the logs
+type Flusher interface {
+	Flush() error
+}
+
+// WriterSink encodes the record by Encoder, and writes to io.Writer
+type WriterSink struct {
+	mu      sync.Mutex
+	encoder Encoder
+	out     io.Writer
+}
+
+// NewWriterSink returns the sink writes to w, encoder is TextEncoder if nil
+func NewWriterSink(w io.Writer, encoder Encoder) *WriterSink {
+	if encoder == nil {
+		encoder = NewTextEncoder()
+	}
+	return &WriterSink{encoder: encoder, out: w}
+}
+
+// stdLogWriter writes by go std log, so `log.SetOutput`, `log.SetFlags` and `log.SetPrefix` still work for
+// the default logger, the time is added by log according to its flags
+type stdLogWriter struct{}
+
+func (stdLogWriter) Write(p []byte) (int, error) {
+	if err := log.Output(2, string(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// _stdLogEncoder is the default encoder for stdLogWriter, without time since go std log adds it
+var _stdLogEncoder = &TextEncoder{}
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+func (s *WriterSink) WriteRecord(r *Record) error {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer bufferPool.Put(buf)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.encoder.Encode(buf, r); err != nil {
+		// should not happen for the builtin encoders, output the error instead of lose the log
+		buf.Reset()
+		_, _ = fmt.Fprintf(buf, "flog: encode record fail, err=%v, level=%s, msg=%q\n", err, r.Level, r.Message)
+	}
+	_, err := s.out.Write(buf.Bytes())
+	return err
+}
+
+func (s *WriterSink) SetEncoder(encoder Encoder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.encoder = encoder
+}
+
+func (s *WriterSink) SetOutput(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// the default TextEncoder adds the time itself unless it writes to go std log, so there is always one time
+	if text, ok := s.encoder.(*TextEncoder); ok && text.Layout == nil {
+		_, toStdLog := w.(stdLogWriter)
+		switch {
+		case toStdLog && text.TimeFormat == defaultTextTimeFormat:
+			s.encoder = _stdLogEncoder
+		case !toStdLog && text == _stdLogEncoder:
+			s.encoder = NewTextEncoder()
+		}
+	}
+	s.out = w
+}
+
+// Flush flushes the output if it's a Flusher(example: bufio.Writer)
+func (s *WriterSink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f, ok := s.out.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Close closes the output if it's an io.Closer(example: RotateFile), except stdout and stderr
+func (s *WriterSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.out == os.Stdout || s.out == os.Stderr {
+		return nil
+	}
+	if c, ok := s.out.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
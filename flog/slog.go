@@ -0,0 +1,276 @@
+//go:build go1.21
+
+package flog
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"time"
+)
+
+// the levels of flog which has no same level in slog
+const (
+	SlogLevelTrace = slog.LevelDebug - 4
+	SlogLevelFatal = slog.LevelError + 4
+	SlogLevelPanic = slog.LevelError + 8
+)
+
+// ToSlogLevel converts flog Level to slog.Level
+func ToSlogLevel(level Level) slog.Level {
+	switch level {
+	case TraceLevel:
+		return SlogLevelTrace
+	case DebugLevel:
+		return slog.LevelDebug
+	case InfoLevel:
+		return slog.LevelInfo
+	case WarnLevel:
+		return slog.LevelWarn
+	case ErrorLevel:
+		return slog.LevelError
+	case FatalLevel:
+		return SlogLevelFatal
+	default:
+		return SlogLevelPanic
+	}
+}
+
+// FromSlogLevel converts slog.Level to flog Level, the level higher than slog.LevelError is Error,
+// so the slog record never exit or panic.
+func FromSlogLevel(level slog.Level) Level {
+	switch {
+	case level < slog.LevelDebug:
+		return TraceLevel
+	case level < slog.LevelInfo:
+		return DebugLevel
+	case level < slog.LevelWarn:
+		return InfoLevel
+	case level < slog.LevelError:
+		return WarnLevel
+	default:
+		return ErrorLevel
+	}
+}
+
+// slogLogger is the ILoggerEx which forwards the logs to slog.Handler
+type slogLogger struct {
+	handler slog.Handler
+	level   *atomicLevel
+}
+
+// NewSlogLogger returns the ILoggerEx which forwards the logs to slog.Handler, example:
+//
+//	handler := slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{AddSource: true})
+//	flog.SetLoggerFactory(func() flog.ILogger { return flog.NewSlogLogger(handler) })
+//
+// the file/line/function passed to XxxExWithPosf(example: from debugutil.Verify) are added as the
+// slog.SourceKey attribute, since there is no pc for them, so it's output even if AddSource is false.
+// the default level is Trace, then filtered by both the level and handler.Enabled.
+func NewSlogLogger(handler slog.Handler) ILoggerEx {
+	return &slogLogger{handler: handler, level: newAtomicLevel(TraceLevel)}
+}
+
+// handle sends the record to handler, pc is 0 when the position is passed by user,
+// force is true when the level is already checked(example: by vmodule)
+func (s *slogLogger) handle(level Level, force bool, pc uintptr, source *slog.Source, format string, args ...any) {
+	ctx := context.Background()
+	slogLevel := ToSlogLevel(level)
+	if (force || s.GetLevel() >= level) && s.handler.Enabled(ctx, slogLevel) {
+		r := slog.NewRecord(time.Now(), slogLevel, formatMessage(format, args), pc)
+		if source != nil {
+			r.AddAttrs(slog.Any(slog.SourceKey, source))
+		}
+		_ = s.handler.Handle(ctx, r)
+	}
+	exitIfFatal(level, format, args...)
+}
+
+func (s *slogLogger) logWithPosf(level Level, fileName string, lineNo int, funName string, format string, args ...any) {
+	if !isEnabledAtPos(s.GetLevel(), level, fileName) {
+		return
+	}
+	s.handle(level, level > FatalLevel, 0, &slog.Source{Function: funName, File: fileName, Line: lineNo}, format, args...)
+}
+
+func (s *slogLogger) outputWithPosf(level Level, fileName string, lineNo int, funName string, format string, args ...any) {
+	s.handle(level, level > FatalLevel, 0, &slog.Source{Function: funName, File: fileName, Line: lineNo}, format, args...)
+}
+
+// logf is called by the method(example: Debugf) which is called by user directly, so skip is 3
+func (s *slogLogger) logf(level Level, format string, args ...any) {
+	if isEnabledAt(s.GetLevel(), level, 3) {
+		var pcs [1]uintptr
+		runtime.Callers(3, pcs[:])
+		s.handle(level, level > FatalLevel, pcs[0], nil, format, args...)
+	}
+}
+
+func (s *slogLogger) Tracef(format string, args ...any) {
+	s.logf(TraceLevel, format, args...)
+}
+
+func (s *slogLogger) Debugf(format string, args ...any) {
+	s.logf(DebugLevel, format, args...)
+}
+
+func (s *slogLogger) Infof(format string, args ...any) {
+	s.logf(InfoLevel, format, args...)
+}
+
+func (s *slogLogger) Warnf(format string, args ...any) {
+	s.logf(WarnLevel, format, args...)
+}
+
+func (s *slogLogger) Errorf(format string, args ...any) {
+	s.logf(ErrorLevel, format, args...)
+}
+
+func (s *slogLogger) Fatalf(format string, args ...any) {
+	s.logf(FatalLevel, format, args...)
+}
+
+func (s *slogLogger) Panicf(format string, args ...any) {
+	s.logf(PanicLevel, format, args...)
+}
+
+func (s *slogLogger) TraceExWithPosf(fileName string, lineNo int, funName string, format string, args ...any) {
+	s.logWithPosf(TraceLevel, fileName, lineNo, funName, format, args...)
+}
+
+func (s *slogLogger) DebugExWithPosf(fileName string, lineNo int, funName string, format string, args ...any) {
+	s.logWithPosf(DebugLevel, fileName, lineNo, funName, format, args...)
+}
+
+func (s *slogLogger) InfoExWithPosf(fileName string, lineNo int, funName string, format string, args ...any) {
+	s.logWithPosf(InfoLevel, fileName, lineNo, funName, format, args...)
+}
+
+func (s *slogLogger) WarnExWithPosf(fileName string, lineNo int, funName string, format string, args ...any) {
+	s.logWithPosf(WarnLevel, fileName, lineNo, funName, format, args...)
+}
+
+func (s *slogLogger) ErrorExWithPosf(fileName string, lineNo int, funName string, format string, args ...any) {
+	s.logWithPosf(ErrorLevel, fileName, lineNo, funName, format, args...)
+}
+
+func (s *slogLogger) FatalExWithPosf(fileName string, lineNo int, funName string, format string, args ...any) {
+	s.logWithPosf(FatalLevel, fileName, lineNo, funName, format, args...)
+}
+
+func (s *slogLogger) PanicExWithPosf(fileName string, lineNo int, funName string, format string, args ...any) {
+	s.logWithPosf(PanicLevel, fileName, lineNo, funName, format, args...)
+}
+
+func (s *slogLogger) SetLevel(level Level) {
+	s.level.Store(level)
+}
+
+func (s *slogLogger) GetLevel() Level {
+	return s.level.Load()
+}
+
+func (s *slogLogger) With(keyValues ...any) ILoggerEx {
+	return s.withFieldList(keyValuesToFields(keyValues))
+}
+
+func (s *slogLogger) WithFields(fields Fields) ILoggerEx {
+	return s.withFieldList(mapToFields(fields))
+}
+
+func (s *slogLogger) withFieldList(fields []Field) *slogLogger {
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, f := range resolveFields(fields) {
+		attrs = append(attrs, slog.Any(f.Key, f.Value))
+	}
+	return &slogLogger{handler: s.handler.WithAttrs(attrs), level: s.level}
+}
+
+// slogHandler is the slog.Handler which writes through the current flog logger
+type slogHandler struct {
+	// fields are from WithAttrs, the key is prefixed with the groups
+	fields []Field
+	groups string
+}
+
+// NewSlogHandler returns the slog.Handler which writes the records through flog's current logger,
+// so the slog and flog share one pipeline, example:
+//
+//	slog.SetDefault(slog.New(flog.NewSlogHandler()))
+//
+// the level is mapped by FromSlogLevel, and the pc of record is converted to file/line/function.
+func NewSlogHandler() slog.Handler {
+	return &slogHandler{}
+}
+
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return curLogger().GetLevel() >= FromSlogLevel(level)
+}
+
+func (h *slogHandler) Handle(_ context.Context, r slog.Record) error {
+	fields := make([]Field, 0, len(h.fields)+r.NumAttrs())
+	fields = append(fields, h.fields...)
+	r.Attrs(func(attr slog.Attr) bool {
+		fields = appendSlogAttr(fields, h.groups, attr)
+		return true
+	})
+
+	fileName, lineNo, funName := "<Unknown>", -1, "<Unknown>"
+	if r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		fileName, lineNo, funName = frame.File, frame.Line, shortFuncName(frame.Function)
+	}
+
+	logger := curLogger()
+	if len(fields) > 0 {
+		logger = logger.With(fieldsToKeyValues(fields)...)
+	}
+	switch FromSlogLevel(r.Level) {
+	case TraceLevel:
+		logger.TraceExWithPosf(fileName, lineNo, funName, "%s", r.Message)
+	case DebugLevel:
+		logger.DebugExWithPosf(fileName, lineNo, funName, "%s", r.Message)
+	case InfoLevel:
+		logger.InfoExWithPosf(fileName, lineNo, funName, "%s", r.Message)
+	case WarnLevel:
+		logger.WarnExWithPosf(fileName, lineNo, funName, "%s", r.Message)
+	default:
+		logger.ErrorExWithPosf(fileName, lineNo, funName, "%s", r.Message)
+	}
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := make([]Field, 0, len(h.fields)+len(attrs))
+	fields = append(fields, h.fields...)
+	for _, attr := range attrs {
+		fields = appendSlogAttr(fields, h.groups, attr)
+	}
+	return &slogHandler{fields: fields, groups: h.groups}
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &slogHandler{fields: h.fields, groups: h.groups + name + "."}
+}
+
+// appendSlogAttr flattens the attr into fields, the key of group member is "group.key"
+func appendSlogAttr(fields []Field, prefix string, attr slog.Attr) []Field {
+	value := attr.Value.Resolve()
+	if value.Kind() == slog.KindGroup {
+		groupPrefix := prefix
+		if attr.Key != "" {
+			groupPrefix = prefix + attr.Key + "."
+		}
+		for _, member := range value.Group() {
+			fields = appendSlogAttr(fields, groupPrefix, member)
+		}
+		return fields
+	}
+	if attr.Key == "" {
+		return fields
+	}
+	return append(fields, Field{Key: prefix + attr.Key, Value: value.Any()})
+}
@@ -0,0 +1,72 @@
+//go:build go1.21
+
+package flog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewSlogLogger(slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+		AddSource: true,
+		Level:     SlogLevelTrace,
+	}))
+	logger.With("upload_id", "u1").WarnExWithPosf("/src/debugutil/verify.go", 42, "Verify", "verify fail: %s", "eof")
+
+	var result map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatalf("invalid json %s, err=%v", buf.String(), err)
+	}
+	source, _ := result["source"].(map[string]any)
+	if result["level"] != "WARN" || result["msg"] != "verify fail: eof" || result["upload_id"] != "u1" ||
+		source["file"] != "/src/debugutil/verify.go" || source["line"] != 42.0 || source["function"] != "Verify" {
+		t.Errorf("wrong json: %s", buf.String())
+	}
+
+	buf.Reset()
+	logger.SetLevel(InfoLevel)
+	logger.Tracef("filtered")
+	logger.Infof("info %d", 1)
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatalf("invalid json %s, err=%v", buf.String(), err)
+	}
+	source, _ = result["source"].(map[string]any)
+	if result["msg"] != "info 1" || !strings.HasSuffix(source["file"].(string), "slog_test.go") {
+		t.Errorf("wrong json: %s", buf.String())
+	}
+}
+
+func TestSlogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	defer SetOutput(stdLogWriter{})
+
+	logger := slog.New(NewSlogHandler())
+	logger.With("a", 1).WithGroup("g").Warn("hello slog", "b", 2, slog.Group("c", "d", 3))
+	logger.Log(context.Background(), SlogLevelTrace, "trace filtered by flog level")
+
+	output := buf.String()
+	if !strings.Contains(output, "[ slog_test.go:") || !strings.Contains(output, "[WARN][a=1 g.b=2 g.c.d=3] hello slog") {
+		t.Errorf("wrong output: %s", output)
+	}
+	if strings.Contains(output, "trace filtered") {
+		t.Errorf("trace should be filtered: %s", output)
+	}
+}
+
+func TestSlogLevel(t *testing.T) {
+	for _, level := range AllLevels {
+		if level > FatalLevel && FromSlogLevel(ToSlogLevel(level)) != level {
+			t.Errorf("wrong level convert: %s", level)
+		}
+	}
+	if FromSlogLevel(SlogLevelPanic) != ErrorLevel {
+		t.Errorf("slog level higher than error should be error")
+	}
+}
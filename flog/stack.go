@@ -0,0 +1,127 @@
+package flog
+
+import (
+	"encoding/json"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Frame is one frame of the call stack
+type Frame struct {
+	Function      string `json:"func"`       // full function name, example: "github.com/fishjam/go-library/debugutil.Verify"
+	ShortFunction string `json:"short_func"` // example: "Verify"
+	Package       string `json:"package"`    // example: "github.com/fishjam/go-library/debugutil"
+	File          string `json:"file"`       // full path of the source file
+	Line          int    `json:"line"`
+}
+
+// defaultStackDepth is the max depth when maxDepth <= 0
+const defaultStackDepth = 32
+
+// _stackFrames is pc => []Frame, one pc may have several frames when the functions are inlined
+var _stackFrames sync.Map
+
+// GetCallStack returns the frames of the call stack, the skip is same as GetCallStackInfo, so
+// GetCallStack(1, 0) starts from the caller. maxDepth <= 0 means 32.
+// the frames of the same pc are cached, so capture the stack at the same place repeatedly is cheap.
+func GetCallStack(skip int, maxDepth int) []Frame {
+	if maxDepth <= 0 {
+		maxDepth = defaultStackDepth
+	}
+	pcs := make([]uintptr, maxDepth)
+	// +1 for runtime.Callers itself
+	n := runtime.Callers(skip+1, pcs)
+	frames := make([]Frame, 0, n)
+	for _, pc := range pcs[:n] {
+		frames = append(frames, framesOfPC(pc)...)
+		if len(frames) >= maxDepth {
+			return frames[:maxDepth]
+		}
+	}
+	return frames
+}
+
+func framesOfPC(pc uintptr) []Frame {
+	if cached, ok := _stackFrames.Load(pc); ok {
+		return cached.([]Frame)
+	}
+	var frames []Frame
+	iter := runtime.CallersFrames([]uintptr{pc})
+	for {
+		f, more := iter.Next()
+		if f.Function != "" || f.File != "" {
+			frames = append(frames, Frame{
+				Function:      f.Function,
+				ShortFunction: shortFuncName(f.Function),
+				Package:       packageOfFunc(f.Function),
+				File:          f.File,
+				Line:          f.Line,
+			})
+		}
+		if !more {
+			break
+		}
+	}
+	_stackFrames.Store(pc, frames)
+	return frames
+}
+
+// FilterRuntimeFrames returns the frames without the go runtime and testing frames
+func FilterRuntimeFrames(frames []Frame) []Frame {
+	result := make([]Frame, 0, len(frames))
+	for _, f := range frames {
+		switch f.Package {
+		case "runtime", "testing":
+			continue
+		}
+		result = append(result, f)
+	}
+	return result
+}
+
+// FormatStackCompact formats the frames in one line from the innermost, example:
+//
+//	verify.go:42 Verify <- upload.go:100 (*Uploader).Upload
+func FormatStackCompact(frames []Frame) string {
+	var sb strings.Builder
+	for i, f := range frames {
+		if i > 0 {
+			sb.WriteString(" <- ")
+		}
+		sb.WriteString(path.Base(f.File))
+		sb.WriteByte(':')
+		sb.WriteString(strconv.Itoa(f.Line))
+		sb.WriteByte(' ')
+		sb.WriteString(f.ShortFunction)
+	}
+	return sb.String()
+}
+
+// FormatStack formats the frames in multi lines like runtime/debug.Stack, example:
+//
+//	github.com/fishjam/go-library/debugutil.Verify
+//		/src/debugutil/verify.go:42
+func FormatStack(frames []Frame) string {
+	var sb strings.Builder
+	for _, f := range frames {
+		sb.WriteString(f.Function)
+		sb.WriteString("\n\t")
+		sb.WriteString(f.File)
+		sb.WriteByte(':')
+		sb.WriteString(strconv.Itoa(f.Line))
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// FormatStackJSON formats the frames as JSON array
+func FormatStackJSON(frames []Frame) string {
+	if frames == nil {
+		frames = []Frame{}
+	}
+	data, _ := json.Marshal(frames)
+	return string(data)
+}
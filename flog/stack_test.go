@@ -0,0 +1,73 @@
+package flog
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func captureStack(maxDepth int) []Frame {
+	return GetCallStack(1, maxDepth)
+}
+
+func TestGetCallStack(t *testing.T) {
+	frames := captureStack(0)
+	if len(frames) < 2 {
+		t.Fatalf("wrong frames %v", frames)
+	}
+	first := frames[0]
+	if first.ShortFunction != "captureStack" || first.Package != "github.com/fishjam/go-library/flog" ||
+		!strings.HasSuffix(first.File, "stack_test.go") || first.Function != first.Package+".captureStack" {
+		t.Errorf("wrong first frame %+v", first)
+	}
+	if frames[1].ShortFunction != "TestGetCallStack" {
+		t.Errorf("wrong second frame %+v", frames[1])
+	}
+	fileName, lineNo, funName := GetCallStackInfo(1)
+	if stack := GetCallStack(1, 1); len(stack) != 1 || stack[0].File != fileName ||
+		stack[0].Line != lineNo+1 || stack[0].ShortFunction != funName {
+		t.Errorf("should be same as GetCallStackInfo, %v", stack)
+	}
+
+	if frames = captureStack(1); len(frames) != 1 {
+		t.Errorf("wrong depth %d", len(frames))
+	}
+	filtered := FilterRuntimeFrames(captureStack(0))
+	for _, f := range filtered {
+		if f.Package == "runtime" || f.Package == "testing" {
+			t.Errorf("should be filtered: %+v", f)
+		}
+	}
+	if len(filtered) != 2 {
+		t.Errorf("wrong filtered frames %v", filtered)
+	}
+}
+
+func TestFormatStack(t *testing.T) {
+	frames := []Frame{
+		{Function: "github.com/fishjam/go-library/debugutil.Verify", ShortFunction: "Verify",
+			Package: "github.com/fishjam/go-library/debugutil", File: "/src/debugutil/verify.go", Line: 42},
+		{Function: "main.main", ShortFunction: "main", Package: "main", File: "/src/main.go", Line: 10},
+	}
+	if compact := FormatStackCompact(frames); compact != "verify.go:42 Verify <- main.go:10 main" {
+		t.Errorf("wrong compact %s", compact)
+	}
+	expected := "github.com/fishjam/go-library/debugutil.Verify\n\t/src/debugutil/verify.go:42\nmain.main\n\t/src/main.go:10\n"
+	if multi := FormatStack(frames); multi != expected {
+		t.Errorf("wrong multi lines %s", multi)
+	}
+	var decoded []Frame
+	if err := json.Unmarshal([]byte(FormatStackJSON(frames)), &decoded); err != nil || len(decoded) != 2 || decoded[1] != frames[1] {
+		t.Errorf("wrong json %v, err=%v", decoded, err)
+	}
+	if FormatStackJSON(nil) != "[]" {
+		t.Errorf("wrong empty json %s", FormatStackJSON(nil))
+	}
+}
+
+func BenchmarkGetCallStack(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = GetCallStack(1, 0)
+	}
+}
@@ -0,0 +1,341 @@
+package flog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// syslog facilities, see RFC 5424 section 6.2.1
+const (
+	FacilityUser   = 1
+	FacilityDaemon = 3
+	FacilityLocal0 = 16
+	FacilityLocal7 = 23
+)
+
+// syslogSeverities maps Level to syslog severity, index is Level
+var syslogSeverities = [...]int{
+	PanicLevel: 1, // alert
+	FatalLevel: 2, // critical
+	ErrorLevel: 3, // error
+	WarnLevel:  4, // warning
+	InfoLevel:  6, // informational
+	DebugLevel: 7, // debug
+	TraceLevel: 7, // debug
+}
+
+// SyslogSeverity returns the syslog severity of the level
+func SyslogSeverity(level Level) int {
+	if !level.IsValid() {
+		return 7
+	}
+	return syslogSeverities[level]
+}
+
+// syslogSDID is the SD-ID of the structured data, 32473 is the example private enterprise number of RFC 5424
+const syslogSDID = "flog@32473"
+
+// localSyslogPaths are the unix sockets of the local syslog daemon
+var localSyslogPaths = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// syslogDial is used to reconnect, replaced in unit test
+var syslogDial = net.DialTimeout
+
+// SyslogConfig is the config of SyslogSink
+type SyslogConfig struct {
+	// Network is "udp", "tcp", "unixgram" or "unix", empty means the local syslog daemon(/dev/log)
+	Network string
+	// Address is the address of syslog server, example: "127.0.0.1:514", or the path of unix socket
+	Address string
+
+	// Facility is the syslog facility, 0 means FacilityUser(the kern facility is only for kernel)
+	Facility int
+	// AppName default is the base name of the program
+	AppName string
+	// Hostname default is os.Hostname()
+	Hostname string
+
+	// BufferSize is the max count of messages buffered while disconnected, the oldest is dropped when full,
+	// default is 1024
+	BufferSize int
+	// ReconnectInterval is the min interval between two dials, default is 1 second
+	ReconnectInterval time.Duration
+	// DialTimeout default is 5 seconds
+	DialTimeout time.Duration
+}
+
+// SyslogSink writes the records in RFC 5424 format to syslog daemon, the fields are in the structured data:
+//
+//	<140>1 2006-01-02T15:04:05.000000Z host app 100 - [flog@32473 file="verify.go" line="42" func="Verify" gid="7" k="v"] msg
+//
+// the stream networks(tcp, unix) use octet-counting framing(RFC 6587), the datagram networks send one message
+// per packet. when the connection fails, the messages are buffered and sent after reconnected.
+// it writes synchronously, wrap it by AsyncSink if the network may be slow.
+type SyslogSink struct {
+	config  SyslogConfig
+	network string // the real network, example: "unixgram" when connect /dev/log
+	address string
+	stream  bool
+
+	mu       sync.Mutex
+	conn     net.Conn
+	lastDial time.Time
+	dialing  bool // one goroutine is dialing without mu, the others buffer their messages meanwhile
+	pending  [][]byte
+	dropped  uint64
+	closed   bool
+}
+
+// NewSyslogSink connects the syslog daemon, returns error if the first connection fails
+func NewSyslogSink(config SyslogConfig) (*SyslogSink, error) {
+	if config.Facility < 0 || config.Facility > FacilityLocal7 {
+		return nil, errors.New("flog: invalid syslog facility " + strconv.Itoa(config.Facility))
+	}
+	if config.Facility == 0 {
+		config.Facility = FacilityUser
+	}
+	if config.AppName == "" {
+		config.AppName = filepath.Base(os.Args[0])
+	}
+	if config.Hostname == "" {
+		config.Hostname, _ = os.Hostname()
+	}
+	if config.BufferSize <= 0 {
+		config.BufferSize = 1024
+	}
+	if config.ReconnectInterval <= 0 {
+		config.ReconnectInterval = time.Second
+	}
+	if config.DialTimeout <= 0 {
+		config.DialTimeout = 5 * time.Second
+	}
+	s := &SyslogSink{config: config}
+
+	var err error
+	if config.Network == "" {
+		s.conn, s.network, s.address, err = dialLocalSyslog(config.Address, config.DialTimeout)
+	} else {
+		s.network, s.address = config.Network, config.Address
+		s.conn, err = net.DialTimeout(s.network, s.address, config.DialTimeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.stream = s.network == "tcp" || s.network == "tcp4" || s.network == "tcp6" || s.network == "unix"
+	s.lastDial = time.Now()
+	return s, nil
+}
+
+// dialLocalSyslog connects the local syslog daemon by unix socket
+func dialLocalSyslog(address string, timeout time.Duration) (conn net.Conn, network string, addr string, err error) {
+	paths := localSyslogPaths
+	if address != "" {
+		paths = []string{address}
+	}
+	for _, network = range []string{"unixgram", "unix"} {
+		for _, addr = range paths {
+			if conn, err = net.DialTimeout(network, addr, timeout); err == nil {
+				return conn, network, addr, nil
+			}
+		}
+	}
+	return nil, "", "", err
+}
+
+func (s *SyslogSink) WriteRecord(r *Record) error {
+	msg := s.format(r)
+	s.reconnect(false)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrSinkClosed
+	}
+	if s.conn != nil {
+		if err := s.send(msg); err == nil {
+			return nil
+		}
+	}
+	s.buffer(msg)
+	return nil
+}
+
+// reconnect dials without mu when disconnected and ReconnectInterval passed(or force), then sends the
+// pending messages, so the slow dial doesn't block the other writers
+func (s *SyslogSink) reconnect(force bool) {
+	s.mu.Lock()
+	if s.closed || s.conn != nil || s.dialing || (!force && time.Since(s.lastDial) < s.config.ReconnectInterval) {
+		s.mu.Unlock()
+		return
+	}
+	s.dialing, s.lastDial = true, time.Now()
+	s.mu.Unlock()
+
+	conn, err := syslogDial(s.network, s.address, s.config.DialTimeout)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dialing = false
+	if err != nil {
+		return
+	}
+	if s.closed {
+		_ = conn.Close()
+		return
+	}
+	s.conn = conn
+	s.sendPending()
+}
+
+// sendPending sends the messages buffered while disconnected, called with mu
+func (s *SyslogSink) sendPending() {
+	for len(s.pending) > 0 {
+		if err := s.send(s.pending[0]); err != nil {
+			return
+		}
+		s.pending[0] = nil
+		s.pending = s.pending[1:]
+	}
+	s.pending = nil
+}
+
+// send writes one message, closes the connection when fail, called with mu
+func (s *SyslogSink) send(msg []byte) error {
+	var err error
+	if s.stream {
+		// octet-counting: "MSG-LEN SP SYSLOG-MSG"
+		frame := make([]byte, 0, len(msg)+8)
+		frame = strconv.AppendInt(frame, int64(len(msg)), 10)
+		frame = append(frame, ' ')
+		_, err = s.conn.Write(append(frame, msg...))
+	} else {
+		_, err = s.conn.Write(msg)
+	}
+	if err != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+	}
+	return err
+}
+
+// buffer keeps the message until reconnected, drops the oldest when full, called with mu
+func (s *SyslogSink) buffer(msg []byte) {
+	if len(s.pending) >= s.config.BufferSize {
+		s.pending[0] = nil
+		s.pending = s.pending[1:]
+		s.dropped++
+	}
+	s.pending = append(s.pending, msg)
+}
+
+// Dropped returns the count of messages dropped because the buffer is full while disconnected
+func (s *SyslogSink) Dropped() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// Flush reconnects at once if disconnected and sends the pending messages, returns error if some are still pending
+func (s *SyslogSink) Flush() error {
+	s.reconnect(true)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil {
+		s.sendPending()
+	}
+	if len(s.pending) > 0 {
+		return fmt.Errorf("flog: %d syslog messages pending, %s %s is disconnected", len(s.pending), s.network, s.address)
+	}
+	return nil
+}
+
+// Close closes the connection, the pending messages are lost if not reconnected(by WriteRecord or Flush)
+func (s *SyslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	if s.conn != nil {
+		err := s.conn.Close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
+
+// format encodes the record as RFC 5424 message
+func (s *SyslogSink) format(r *Record) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte('<')
+	buf.WriteString(strconv.Itoa(s.config.Facility*8 + SyslogSeverity(r.Level)))
+	buf.WriteString(">1 ")
+	buf.WriteString(r.Time.Format("2006-01-02T15:04:05.000000Z07:00"))
+	buf.WriteByte(' ')
+	buf.WriteString(syslogHeaderValue(s.config.Hostname, 255))
+	buf.WriteByte(' ')
+	buf.WriteString(syslogHeaderValue(s.config.AppName, 48))
+	buf.WriteByte(' ')
+	buf.WriteString(strconv.Itoa(r.Pid))
+	buf.WriteString(" - [")
+	buf.WriteString(syslogSDID)
+	appendSyslogParam(&buf, "file", filepath.Base(r.File))
+	appendSyslogParam(&buf, "line", strconv.Itoa(r.Line))
+	appendSyslogParam(&buf, "func", r.Function)
+	appendSyslogParam(&buf, "gid", strconv.FormatUint(r.GoroutineID, 10))
+	for _, f := range r.Fields {
+		appendSyslogParam(&buf, f.Key, fieldValueString(f.Value))
+	}
+	buf.WriteString("] ")
+	buf.WriteString(r.Message)
+	return buf.Bytes()
+}
+
+// syslogHeaderValue returns the printable US-ASCII value, or "-" if empty
+func syslogHeaderValue(s string, maxLen int) string {
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s) && len(b) < maxLen; i++ {
+		if s[i] > ' ' && s[i] < 0x7f {
+			b = append(b, s[i])
+		}
+	}
+	if len(b) == 0 {
+		return "-"
+	}
+	return string(b)
+}
+
+// appendSyslogParam appends ` name="value"`, the invalid chars of name are replaced by '_',
+// and '"', '\' and ']' of value are escaped
+func appendSyslogParam(buf *bytes.Buffer, name string, value string) {
+	buf.WriteByte(' ')
+	nameLen := 0
+	for i := 0; i < len(name) && nameLen < 32; i++ {
+		c := name[i]
+		if c <= ' ' || c >= 0x7f || c == '=' || c == ']' || c == '"' {
+			c = '_'
+		}
+		buf.WriteByte(c)
+		nameLen++
+	}
+	if nameLen == 0 {
+		buf.WriteByte('_')
+	}
+	buf.WriteString(`="`)
+	for _, c := range value {
+		switch c {
+		case '"', '\\', ']':
+			buf.WriteByte('\\')
+			buf.WriteRune(c)
+		default:
+			buf.WriteRune(c)
+		}
+	}
+	buf.WriteByte('"')
+}
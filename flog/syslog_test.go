@@ -0,0 +1,262 @@
+package flog
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newSyslogTestRecord(level Level, msg string) *Record {
+	r := newTestRecord()
+	r.Level, r.Message = level, msg
+	r.Fields = []Field{{"upload id", `a"b]`}}
+	return r
+}
+
+func TestSyslogFormat(t *testing.T) {
+	s := &SyslogSink{config: SyslogConfig{Facility: FacilityLocal0, Hostname: "host", AppName: "app"}}
+	msg := string(s.format(newSyslogTestRecord(WarnLevel, "verify fail")))
+	expected := `<132>1 2023-05-06T07:08:09.123456Z host app 100 - [flog@32473 file="verify.go" line="42" ` +
+		`func="checkAndHandleError" gid="7" upload_id="a\"b\]"] verify fail`
+	if msg != expected {
+		t.Errorf("syslog=%s\nexpected=%s", msg, expected)
+	}
+	if SyslogSeverity(PanicLevel) != 1 || SyslogSeverity(TraceLevel) != 7 || SyslogSeverity(Level(100)) != 7 {
+		t.Errorf("wrong severity")
+	}
+}
+
+func TestSyslogSinkTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("listen fail: %v", err)
+	}
+	defer ln.Close()
+	received := make(chan string, 10)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		for {
+			// octet-counting: "MSG-LEN SP SYSLOG-MSG"
+			lenText, err := reader.ReadString(' ')
+			if err != nil {
+				return
+			}
+			size, _ := strconv.Atoi(strings.TrimSpace(lenText))
+			msg := make([]byte, size)
+			if _, err = io.ReadFull(reader, msg); err != nil {
+				return
+			}
+			received <- string(msg)
+		}
+	}()
+
+	s, err := NewSyslogSink(SyslogConfig{Network: "tcp", Address: ln.Addr().String(), AppName: "test"})
+	if err != nil {
+		t.Fatalf("new syslog sink fail: %v", err)
+	}
+	defer s.Close()
+	l := &defaultLogger{core: newLoggerCore(InfoLevel, s)}
+	l.Errorf("first")
+	l.With("k", "v").Infof("second\nline")
+	for _, expected := range []string{`<11>1 `, `<14>1 `} {
+		select {
+		case msg := <-received:
+			if !strings.HasPrefix(msg, expected) || !strings.Contains(msg, " test ") {
+				t.Errorf("wrong message %s, expected prefix %s", msg, expected)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout")
+		}
+	}
+}
+
+func TestSyslogSinkUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("listen fail: %v", err)
+	}
+	defer conn.Close()
+	s, err := NewSyslogSink(SyslogConfig{Network: "udp", Address: conn.LocalAddr().String()})
+	if err != nil {
+		t.Fatalf("new syslog sink fail: %v", err)
+	}
+	defer s.Close()
+	_ = s.WriteRecord(newSyslogTestRecord(DebugLevel, "udp"))
+
+	buf := make([]byte, 4096)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil || !strings.HasPrefix(string(buf[:n]), "<15>1 ") || !strings.HasSuffix(string(buf[:n]), "] udp") {
+		t.Errorf("wrong message %q, err=%v", buf[:n], err)
+	}
+}
+
+func TestSyslogSinkReconnect(t *testing.T) {
+	// short path, the max length of unix socket path is about 100
+	dir, err := os.MkdirTemp("", "flog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sockPath := filepath.Join(dir, "log")
+
+	listen := func() *net.UnixConn {
+		conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: sockPath, Net: "unixgram"})
+		if err != nil {
+			t.Skipf("listen unixgram fail: %v", err)
+		}
+		return conn
+	}
+	server := listen()
+	s, err := NewSyslogSink(SyslogConfig{Address: sockPath, BufferSize: 2, ReconnectInterval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("new syslog sink fail: %v", err)
+	}
+	defer s.Close()
+	if s.network != "unixgram" || s.stream {
+		t.Errorf("wrong network %s", s.network)
+	}
+
+	// the daemon restarts, the messages are buffered
+	_ = server.Close()
+	_ = os.Remove(sockPath)
+	for i := 0; i < 3; i++ {
+		_ = s.WriteRecord(newSyslogTestRecord(InfoLevel, "buffered"+strconv.Itoa(i)))
+	}
+	if s.Dropped() != 1 {
+		t.Errorf("wrong dropped count %d", s.Dropped())
+	}
+
+	server = listen()
+	defer server.Close()
+	time.Sleep(2 * time.Millisecond)
+	_ = s.WriteRecord(newSyslogTestRecord(InfoLevel, "after"))
+
+	buf := make([]byte, 4096)
+	var messages []string
+	_ = server.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for len(messages) < 3 {
+		n, err := server.Read(buf)
+		if err != nil {
+			t.Fatalf("read fail: %v, messages=%v", err, messages)
+		}
+		msg := string(buf[:n])
+		messages = append(messages, msg[strings.LastIndexByte(msg, ' ')+1:])
+	}
+	if strings.Join(messages, ",") != "buffered1,buffered2,after" {
+		t.Errorf("wrong messages %v", messages)
+	}
+}
+
+func TestSyslogSinkFlush(t *testing.T) {
+	dir, err := os.MkdirTemp("", "flog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sockPath := filepath.Join(dir, "log")
+
+	listen := func() *net.UnixConn {
+		conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: sockPath, Net: "unixgram"})
+		if err != nil {
+			t.Skipf("listen unixgram fail: %v", err)
+		}
+		return conn
+	}
+	server := listen()
+	// the interval is long, only Flush reconnects
+	s, err := NewSyslogSink(SyslogConfig{Address: sockPath, ReconnectInterval: time.Hour, DialTimeout: time.Second})
+	if err != nil {
+		t.Fatalf("new syslog sink fail: %v", err)
+	}
+	defer s.Close()
+
+	_ = server.Close()
+	_ = os.Remove(sockPath)
+	_ = s.WriteRecord(newSyslogTestRecord(InfoLevel, "pending"))
+	if s.Flush() == nil {
+		t.Errorf("flush should fail while the daemon is down")
+	}
+
+	server = listen()
+	defer server.Close()
+	if err = s.Flush(); err != nil {
+		t.Fatalf("flush fail: %v", err)
+	}
+	buf := make([]byte, 4096)
+	_ = server.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := server.Read(buf)
+	if err != nil || !strings.HasSuffix(string(buf[:n]), "] pending") {
+		t.Errorf("wrong message %q, err=%v", buf[:n], err)
+	}
+}
+
+func TestSyslogSinkWriteWhileDialing(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	s, err := NewSyslogSink(SyslogConfig{Network: "udp", Address: conn.LocalAddr().String(), ReconnectInterval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	release := make(chan struct{})
+	defer func() { syslogDial = net.DialTimeout }()
+	syslogDial = func(network, address string, timeout time.Duration) (net.Conn, error) {
+		<-release
+		return net.DialTimeout(network, address, timeout)
+	}
+	s.mu.Lock()
+	_ = s.conn.Close()
+	s.conn = nil
+	s.mu.Unlock()
+	time.Sleep(2 * time.Millisecond)
+
+	// the first writer dials, the second one is not blocked by the slow dial
+	dialed := make(chan struct{})
+	go func() {
+		_ = s.WriteRecord(newSyslogTestRecord(InfoLevel, "first"))
+		close(dialed)
+	}()
+	waitFor(t, "dialing", func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.dialing
+	})
+	start := time.Now()
+	_ = s.WriteRecord(newSyslogTestRecord(InfoLevel, "second"))
+	if cost := time.Since(start); cost > time.Second {
+		t.Errorf("WriteRecord is blocked by the dial for %v", cost)
+	}
+	close(release)
+	<-dialed
+
+	buf := make([]byte, 4096)
+	var messages []string
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for len(messages) < 2 {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("read fail: %v, messages=%v", err, messages)
+		}
+		msg := string(buf[:n])
+		messages = append(messages, msg[strings.LastIndexByte(msg, ' ')+1:])
+	}
+	if strings.Join(messages, ",") != "second,first" {
+		t.Errorf("wrong messages %v", messages)
+	}
+}
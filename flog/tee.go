@@ -0,0 +1,119 @@
+package flog
+
+import "io"
+
+// TeeEntry is one output of TeeSink
+type TeeEntry struct {
+	// Sink is the output, example: NewWriterSink(os.Stderr, NewTextEncoder()),
+	// or NewWriterSink(rotateFile, NewJSONEncoder())
+	Sink Sink
+
+	// Level is the most verbose level written to the sink, example: WarnLevel skips Info/Debug/Trace
+	Level Level
+
+	// OnError is called when the sink returns error, can be nil, then the error is returned by TeeSink.WriteRecord.
+	// it's called in the background goroutine when Async is set.
+	OnError func(r *Record, err error)
+
+	// Async wraps the sink with AsyncSink when not nil, so the slow sink doesn't block the others
+	Async *AsyncOptions
+}
+
+// TeeSink writes every record to several sinks, each sink has its own level, encoder and error handling,
+// the error of one sink doesn't stop the others, example:
+//
+//	flog.EnableTee(
+//		flog.TeeEntry{Sink: flog.NewWriterSink(os.Stderr, flog.NewTextEncoder()), Level: flog.WarnLevel},
+//		flog.TeeEntry{Sink: flog.NewWriterSink(rotateFile, flog.NewJSONEncoder()), Level: flog.DebugLevel,
+//			Async: &flog.AsyncOptions{FullPolicy: flog.FullDropOldest}},
+//	)
+type TeeSink struct {
+	entries []TeeEntry
+}
+
+// NewTeeSink returns the TeeSink, the sink of entry is wrapped by AsyncSink if Async is set
+func NewTeeSink(entries ...TeeEntry) *TeeSink {
+	ts := &TeeSink{entries: make([]TeeEntry, 0, len(entries))}
+	for _, entry := range entries {
+		if entry.Async != nil {
+			options := *entry.Async
+			if options.OnError == nil {
+				options.OnError = entry.OnError
+			}
+			entry.Sink = NewAsyncSink(entry.Sink, options)
+		}
+		ts.entries = append(ts.entries, entry)
+	}
+	return ts
+}
+
+// MaxLevel returns the most verbose level of all the entries
+func (ts *TeeSink) MaxLevel() Level {
+	level := PanicLevel
+	for _, entry := range ts.entries {
+		if entry.Level > level {
+			level = entry.Level
+		}
+	}
+	return level
+}
+
+// WriteRecord writes the record to all the sinks whose level is enabled,
+// returns the first error of the sinks without OnError.
+func (ts *TeeSink) WriteRecord(r *Record) error {
+	var firstErr error
+	for _, entry := range ts.entries {
+		if r.Level > entry.Level {
+			continue
+		}
+		if err := entry.Sink.WriteRecord(r); err != nil {
+			if entry.OnError != nil {
+				entry.OnError(r, err)
+			} else if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// Flush flushes all the sinks which are Flusher, returns the first error
+func (ts *TeeSink) Flush() error {
+	var firstErr error
+	for _, entry := range ts.entries {
+		if f, ok := entry.Sink.(Flusher); ok {
+			if err := f.Flush(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// Close closes(or flushes) all the sinks, returns the first error
+func (ts *TeeSink) Close() error {
+	var firstErr error
+	for _, entry := range ts.entries {
+		var err error
+		if c, ok := entry.Sink.(io.Closer); ok {
+			err = c.Close()
+		} else if f, ok := entry.Sink.(Flusher); ok {
+			err = f.Flush()
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// EnableTee replaces the sink of default logger with TeeSink, and sets the level(by SetLevel, and of the default
+// logger if it's not current) to the most verbose level of the entries, so every sink receives the records it wants.
+// the later SetLevel caps the levels of the entries. call `flog.Close()` before program exit.
+func EnableTee(entries ...TeeEntry) *TeeSink {
+	ts := NewTeeSink(entries...)
+	SetSink(ts)
+	SetLevel(ts.MaxLevel())
+	_defaultLogger.SetLevel(ts.MaxLevel())
+	return ts
+}
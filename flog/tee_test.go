@@ -0,0 +1,93 @@
+package flog
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// errorSink always fails, like the disk is full
+type errorSink struct {
+	mu    sync.Mutex
+	count int
+}
+
+func (s *errorSink) WriteRecord(r *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.count++
+	return errors.New("no space left on device")
+}
+
+func TestTeeSink(t *testing.T) {
+	var textBuf, jsonBuf bytes.Buffer
+	failing := &errorSink{}
+	var errMu sync.Mutex
+	var errCount int
+	ts := NewTeeSink(
+		TeeEntry{Sink: NewWriterSink(&textBuf, NewTextEncoder()), Level: WarnLevel},
+		TeeEntry{Sink: failing, Level: TraceLevel, Async: &AsyncOptions{}, OnError: func(r *Record, err error) {
+			errMu.Lock()
+			defer errMu.Unlock()
+			errCount++
+		}},
+		TeeEntry{Sink: NewWriterSink(&jsonBuf, NewJSONEncoder()), Level: DebugLevel},
+	)
+	if ts.MaxLevel() != TraceLevel {
+		t.Errorf("wrong max level %s", ts.MaxLevel())
+	}
+	l := &defaultLogger{core: newLoggerCore(ts.MaxLevel(), ts)}
+	l.Warnf("verify fail")
+	l.Debugf("detail")
+	l.Tracef("trace")
+	if err := ts.Close(); err != nil {
+		t.Errorf("close fail: %v", err)
+	}
+
+	if strings.Count(textBuf.String(), "\n") != 1 || !strings.Contains(textBuf.String(), "[WARN][none] verify fail") {
+		t.Errorf("wrong text output: %s", textBuf.String())
+	}
+	if strings.Count(jsonBuf.String(), "\n") != 2 || !strings.Contains(jsonBuf.String(), `"msg":"detail"`) {
+		t.Errorf("wrong json output: %s", jsonBuf.String())
+	}
+	if failing.count != 3 || errCount != 3 {
+		t.Errorf("wrong error count, write=%d, error=%d", failing.count, errCount)
+	}
+}
+
+func TestTeeSinkError(t *testing.T) {
+	var buf bytes.Buffer
+	ts := NewTeeSink(
+		TeeEntry{Sink: &errorSink{}, Level: InfoLevel},
+		TeeEntry{Sink: NewWriterSink(&buf, NewLogfmtEncoder()), Level: InfoLevel},
+	)
+	if err := ts.WriteRecord(&Record{Level: InfoLevel, Message: "hello"}); err == nil {
+		t.Errorf("should return the error of the sink without OnError")
+	}
+	if !strings.Contains(buf.String(), "msg=hello") {
+		t.Errorf("the error should not stop other sinks: %s", buf.String())
+	}
+}
+
+func TestEnableTee(t *testing.T) {
+	oldSink, oldLevel := GetSink(), _defaultLogger.GetLevel()
+	oldGlobalLevel, oldLevelSet := _globalLevel, _levelSet
+	defer func() {
+		SetSink(oldSink)
+		_defaultLogger.SetLevel(oldLevel)
+		_globalLevel, _levelSet = oldGlobalLevel, oldLevelSet
+	}()
+
+	var buf bytes.Buffer
+	EnableTee(TeeEntry{Sink: NewWriterSink(&buf, nil), Level: InfoLevel})
+	// the level is kept for the logger set by SetLoggerFactory later
+	if _defaultLogger.GetLevel() != InfoLevel || GetLevel() != InfoLevel || _globalLevel != InfoLevel || !_levelSet {
+		t.Errorf("wrong level %s, global level %s", _defaultLogger.GetLevel(), _globalLevel)
+	}
+	Infof("tee")
+	if !strings.Contains(buf.String(), "tee") {
+		t.Errorf("wrong output: %s", buf.String())
+	}
+}
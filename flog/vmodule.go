@@ -0,0 +1,239 @@
+package flog
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// EnvVModule is the environment variable of the module level overrides, read when init, example:
+//
+//	FLOG_VMODULE=repeatable_reader=5,virtual_writer=3,github.com/fishjam/go-library/mime/*=info
+const EnvVModule = "FLOG_VMODULE"
+
+// moduleRule is one "pattern=level" of vmodule, the pattern(path.Match syntax) is matched against:
+//   - the base name of the caller file without ".go", example: "repeatable_reader"
+//   - the package path of the caller, example: "github.com/fishjam/go-library/ioext"
+//   - the last element of the package path, example: "ioext"
+type moduleRule struct {
+	pattern string
+	level   Level
+}
+
+type vmoduleConfig struct {
+	spec  string
+	rules []moduleRule
+
+	// maxLevel is the max level of all rules, the log higher than it and logger's level needn't lookup callsite
+	maxLevel Level
+
+	// cache is pc => *pcLevel, so every callsite only matches the rules once
+	cache sync.Map
+}
+
+// pcLevel is the cached result of a callsite, matched is false when no rule matched
+type pcLevel struct {
+	level   Level
+	matched bool
+}
+
+// _vmodule is *vmoduleConfig, nil when there is no rules, so the check is only one atomic load
+var _vmodule atomic.Value
+
+func init() {
+	if spec := os.Getenv(EnvVModule); spec != "" {
+		if err := SetVModule(spec); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "flog: invalid %s=%q, err=%v\n", EnvVModule, spec, err)
+		}
+	}
+}
+
+// SetVModule set the module level overrides like glog's -vmodule, the rules are separated by comma,
+// and the first matched rule is used. the level can be name or number, example:
+//
+//	flog.SetVModule("repeatable_reader=5,virtual_writer=warn")
+//
+// the override can be higher(output more debug) or lower(suppress noisy logs) than the logger's level,
+// but the logger created by SetLoggerFactory which implements ILoggerEx still filters by its own level.
+// the position passed to XxxExWithPosf is matched by the base name of the file only.
+// empty spec clears all the rules.
+func SetVModule(spec string) error {
+	config, err := parseVModule(spec)
+	if err != nil {
+		return err
+	}
+	_vmodule.Store(config)
+	return nil
+}
+
+// SetModuleLevel append(or replace) one rule of the vmodule
+func SetModuleLevel(pattern string, level Level) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("flog: invalid vmodule pattern %q: %w", pattern, err)
+	}
+	var rules []moduleRule
+	if config := loadVModule(); config != nil {
+		for _, rule := range config.rules {
+			if rule.pattern != pattern {
+				rules = append(rules, rule)
+			}
+		}
+	}
+	_vmodule.Store(newVModuleConfig(append(rules, moduleRule{pattern: pattern, level: level})))
+	return nil
+}
+
+// GetVModule returns the current vmodule spec
+func GetVModule() string {
+	if config := loadVModule(); config != nil {
+		return config.spec
+	}
+	return ""
+}
+
+func loadVModule() *vmoduleConfig {
+	config, _ := _vmodule.Load().(*vmoduleConfig)
+	return config
+}
+
+func parseVModule(spec string) (*vmoduleConfig, error) {
+	var rules []moduleRule
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		pos := strings.LastIndexByte(item, '=')
+		if pos <= 0 {
+			return nil, fmt.Errorf("flog: invalid vmodule rule %q, should be pattern=level", item)
+		}
+		pattern := strings.TrimSpace(item[:pos])
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("flog: invalid vmodule pattern %q: %w", pattern, err)
+		}
+		level, err := ParseLevel(item[pos+1:])
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, moduleRule{pattern: pattern, level: level})
+	}
+	return newVModuleConfig(rules), nil
+}
+
+func newVModuleConfig(rules []moduleRule) *vmoduleConfig {
+	if len(rules) == 0 {
+		// typed nil, so loadVModule returns nil
+		return nil
+	}
+	config := &vmoduleConfig{rules: rules}
+	specs := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		specs = append(specs, rule.pattern+"="+rule.level.String())
+		if rule.level > config.maxLevel {
+			config.maxLevel = rule.level
+		}
+	}
+	config.spec = strings.Join(specs, ",")
+	return config
+}
+
+// isEnabledAtPos is isEnabledAt for the position passed by user(XxxExWithPosf), which has no pc,
+// so the vmodule rules are matched against the base name of fileName only.
+func isEnabledAtPos(loggerLevel Level, level Level, fileName string) bool {
+	if level <= FatalLevel {
+		return true
+	}
+	if config := loadVModule(); config != nil && (level <= config.maxLevel || level <= loggerLevel) {
+		if moduleLevel, ok := config.matchFile(fileName); ok {
+			return moduleLevel >= level
+		}
+	}
+	return loggerLevel >= level
+}
+
+// levelOf returns the override level of the callsite
+func (config *vmoduleConfig) levelOf(pc uintptr) (Level, bool) {
+	if cached, ok := config.cache.Load(pc); ok {
+		result := cached.(*pcLevel)
+		return result.level, result.matched
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	result := &pcLevel{}
+	result.level, result.matched = config.match(frame.File, frame.Function)
+	config.cache.Store(pc, result)
+	return result.level, result.matched
+}
+
+func (config *vmoduleConfig) match(fileName string, funcName string) (Level, bool) {
+	module := strings.TrimSuffix(path.Base(fileName), ".go")
+	pkgPath := packageOfFunc(funcName)
+	for _, rule := range config.rules {
+		for _, name := range [...]string{module, pkgPath, path.Base(pkgPath)} {
+			if matched, _ := path.Match(rule.pattern, name); matched && name != "" {
+				return rule.level, true
+			}
+		}
+	}
+	return PanicLevel, false
+}
+
+// matchFile matches the rules against the base name of fileName without ".go"
+func (config *vmoduleConfig) matchFile(fileName string) (Level, bool) {
+	module := strings.TrimSuffix(path.Base(fileName), ".go")
+	for _, rule := range config.rules {
+		if matched, _ := path.Match(rule.pattern, module); matched && module != "" {
+			return rule.level, true
+		}
+	}
+	return PanicLevel, false
+}
+
+// packageOfFunc returns the package path of the full function name,
+// example: "github.com/fishjam/go-library/mime/multipart.(*VirtualWriter).Read" => "github.com/fishjam/go-library/mime/multipart"
+func packageOfFunc(funcName string) string {
+	lastSlash := strings.LastIndexByte(funcName, '/')
+	if dot := strings.IndexByte(funcName[lastSlash+1:], '.'); dot >= 0 {
+		return funcName[:lastSlash+1+dot]
+	}
+	return funcName
+}
+
+// isEnabledAt checks whether the log is enabled at the callsite, the skip is same as GetCallStackInfo(skip)
+// if called at the same place. Fatal and Panic are always enabled, so they can exit or panic.
+// the callsite enabled by EnableCallsites is always enabled, and disabled by DisableCallsites is always disabled.
+func isEnabledAt(loggerLevel Level, level Level, skip int) bool {
+	if level <= FatalLevel {
+		return true
+	}
+	registry := loadCallsiteRegistry()
+	config := loadVModule()
+	if registry == nil && (config == nil || (level > config.maxLevel && level > loggerLevel)) {
+		return loggerLevel >= level
+	}
+	if registry != nil && !registry.canEnable() && level > loggerLevel && (config == nil || level > config.maxLevel) {
+		// disabled by level, and no rule could enable it, so needn't lookup(and record) the callsite
+		return false
+	}
+	var pcs [1]uintptr
+	if runtime.Callers(skip+1, pcs[:]) == 0 {
+		return loggerLevel >= level
+	}
+	if registry != nil {
+		switch registry.hit(pcs[0], level) {
+		case callsiteOn:
+			return true
+		case callsiteOff:
+			return false
+		}
+	}
+	if config != nil {
+		if moduleLevel, ok := config.levelOf(pcs[0]); ok {
+			return moduleLevel >= level
+		}
+	}
+	return loggerLevel >= level
+}
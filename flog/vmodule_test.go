@@ -0,0 +1,117 @@
+package flog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseVModule(t *testing.T) {
+	config, err := parseVModule(" repeatable_reader=5, github.com/fishjam/go-library/mime/*=warn ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.spec != "repeatable_reader=DEBUG,github.com/fishjam/go-library/mime/*=WARN" {
+		t.Errorf("wrong spec: %s", config.spec)
+	}
+	for _, spec := range []string{"abc", "=5", "abc=verbose", "[=5"} {
+		if _, err = parseVModule(spec); err == nil {
+			t.Errorf("parse %q should fail", spec)
+		}
+	}
+
+	cases := []struct {
+		file     string
+		funcName string
+		level    Level
+		matched  bool
+	}{
+		{"/src/ioext/repeatable_reader.go", "github.com/fishjam/go-library/ioext.NewRepeatableReader", DebugLevel, true},
+		{"/src/mime/multipart/virtual_writer.go", "github.com/fishjam/go-library/mime/multipart.(*VirtualWriter).Read", WarnLevel, true},
+		{"/src/debugutil/verify.go", "github.com/fishjam/go-library/debugutil.Verify", PanicLevel, false},
+	}
+	for _, c := range cases {
+		level, matched := config.match(c.file, c.funcName)
+		if level != c.level || matched != c.matched {
+			t.Errorf("match %s: level=%s, matched=%v", c.file, level, matched)
+		}
+	}
+}
+
+func TestVModule(t *testing.T) {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	oldLevel := GetLevel()
+	defer func() {
+		SetOutput(stdLogWriter{})
+		SetLevel(oldLevel)
+		_ = SetVModule("")
+	}()
+
+	SetLevel(WarnLevel)
+	_ = SetVModule("vmodule_test=debug")
+	Debugf("package debug")
+	With("k", "v").Debugf("logger debug")
+	Tracef("package trace")
+
+	// match by last element of package path, lower than the logger's level
+	_ = SetModuleLevel("flog", ErrorLevel)
+	if GetVModule() != "vmodule_test=DEBUG,flog=ERROR" {
+		t.Errorf("wrong vmodule: %s", GetVModule())
+	}
+	_ = SetVModule("flog=error")
+	Warnf("suppressed warn")
+	Errorf("package error")
+
+	output := buf.String()
+	for _, expected := range []string{"package debug", "logger debug", "package error"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("output should contain %q: %s", expected, output)
+		}
+	}
+	for _, unexpected := range []string{"package trace", "suppressed warn"} {
+		if strings.Contains(output, unexpected) {
+			t.Errorf("output should not contain %q: %s", unexpected, output)
+		}
+	}
+}
+
+func TestVModuleWithPos(t *testing.T) {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	oldLevel := GetLevel()
+	defer func() {
+		SetOutput(stdLogWriter{})
+		SetLevel(oldLevel)
+		_ = SetVModule("")
+	}()
+
+	// the explicit position is matched by the file name
+	SetLevel(WarnLevel)
+	_ = SetVModule("verify=debug,noisy=error")
+	DebugExWithPosf("/src/debugutil/verify.go", 1, "Verify", "verify debug")
+	DebugExWithPosf("/src/other.go", 1, "Other", "other debug")
+	WarnExWithPosf("/src/noisy.go", 1, "Noisy", "noisy warn")
+	GetLogger("vmodule_test").DebugExWithPosf("verify.go", 2, "Verify", "named debug")
+
+	output := buf.String()
+	if !strings.Contains(output, "verify debug") || !strings.Contains(output, "named debug") ||
+		strings.Contains(output, "other debug") || strings.Contains(output, "noisy warn") {
+		t.Errorf("wrong output: %s", output)
+	}
+}
+
+func BenchmarkDisabledDebugWithVModule(b *testing.B) {
+	oldLevel := GetLevel()
+	defer func() {
+		SetLevel(oldLevel)
+		_ = SetVModule("")
+	}()
+	SetLevel(InfoLevel)
+	_ = SetVModule("virtual_writer=debug")
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Debugf("disabled debug")
+	}
+}
@@ -12,6 +12,8 @@ import (
 
 // https://blog.csdn.net/zhang197093/article/details/127407838
 
+var logger = flog.GetLogger("ioext")
+
 // 可重复读取的 Reader, 可用于 http 中再次发送请求等.
 type RepeatableReader struct {
 	orgReader  io.Reader
@@ -21,7 +23,9 @@ type RepeatableReader struct {
 }
 
 func NewRepeatableReader(reader io.Reader) *RepeatableReader {
-	flog.Debugf("reader type=%s", reflect.TypeOf(reader).String())
+	if flog.EnabledFor(logger, flog.DebugLevel) {
+		logger.Debugf("reader type=%s", reflect.TypeOf(reader).String())
+	}
 	readerFunc, length, err := getBodyReaderAndContentLength(reader)
 	if err != nil {
 		return nil
@@ -42,7 +46,7 @@ func (rr *RepeatableReader) Read(p []byte) (n int, err error) {
 
 func (rr *RepeatableReader) Close() error {
 	if closer, ok := rr.orgReader.(io.Closer); ok {
-		flog.Debugf("enter RepeatableReader.Close")
+		logger.Debugf("enter RepeatableReader.Close")
 		return closer.Close()
 	}
 	return nil
@@ -69,7 +73,9 @@ func getBodyReaderAndContentLength(rawBody interface{}) (ReaderFunc, int64, erro
 	var bodyReader ReaderFunc
 	var contentLength int64
 
-	flog.Debugf("readerFunc rawBody type=%s", reflect.TypeOf(rawBody).String())
+	if flog.EnabledFor(logger, flog.DebugLevel) {
+		logger.Debugf("readerFunc rawBody type=%s", reflect.TypeOf(rawBody).String())
+	}
 
 	switch body := rawBody.(type) {
 	// If they gave us a function already, great! Use it.
@@ -62,3 +62,15 @@ func TestRepeatableReader(t *testing.T) {
 		_ = debugutil.Verify(repeatableReader.Close())
 	}
 }
+
+func BenchmarkNewRepeatableReaderDebugDisabled(b *testing.B) {
+	oldLevel := flog.GetLevel()
+	flog.SetLevel(flog.InfoLevel)
+	defer flog.SetLevel(oldLevel)
+
+	data := []byte("fishjam")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = NewRepeatableReader(bytes.NewReader(data))
+	}
+}
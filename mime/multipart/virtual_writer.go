@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/fishjam/go-library/debugutil"
+	"github.com/fishjam/go-library/flog"
 	"io"
 	"os"
 	"path/filepath"
@@ -17,6 +18,10 @@ import (
 // but failed to return an explicit error.
 var ErrWrongParam = errors.New("wrong param")
 
+// logger is passed to debugutil, so the verify fails of multipart can be configured by
+// flog.SetLoggerLevel("multipart", ...) separately
+var logger = flog.GetLogger("multipart")
+
 // OnProgressCallback provide progress callback when do real POST, it's useful when uploading large files.
 //
 // Notice: the part is nil and err is EOF when send last end boundary.
@@ -323,7 +328,7 @@ func (vw *VirtualWriter) Seek(offset int64, whence int) (int64, error) {
 		}
 
 		for _, part := range vw.parts {
-			_ = debugutil.Verify(part.seekToStart())
+			_ = debugutil.VerifyWithConfig(part.seekToStart(), &debugutil.Config{Logger: logger})
 		}
 		vw.readCount = 0
 		vw.readPartIndex = 0